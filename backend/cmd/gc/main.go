@@ -0,0 +1,70 @@
+// Command gc runs the orphaned-object collector once and prints its report as JSON.
+// Objects younger than GC_GRACE_PERIOD are kept.
+//
+// Usage: go run ./cmd/gc [-dry-run]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+
+	sqlc "main/db/sqlc"
+	"main/service/gc"
+	"main/service/spaces"
+	"main/utils"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report orphaned objects without deleting them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Error loading .env file: %v\n", err)
+	}
+
+	logger := utils.Logger()
+	defer func() { _ = logger.Sync() }()
+
+	config, err := utils.LoadConfig()
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		logger.Fatal("Failed to create connection pool", zap.Error(err))
+	}
+	defer pool.Close()
+
+	resumeStore, err := spaces.OpenResumeStore(ctx, config, logger)
+	if err != nil {
+		logger.Fatal("Failed to open resume bucket", zap.Error(err))
+	}
+	webpStore, err := spaces.OpenWebpStore(ctx, config, logger)
+	if err != nil {
+		logger.Fatal("Failed to open webp bucket", zap.Error(err))
+	}
+	resumeBucket := spaces.NewResumeBucket(resumeStore, config.Resume.PresignTTL, logger)
+	webpBucket := spaces.NewWebpBucket(webpStore, config.Webp.CDNBaseURL, logger)
+
+	collector := gc.NewCollector(sqlc.New(pool), resumeBucket, webpBucket, config.GC.GracePeriod, logger)
+	report, err := collector.Collect(ctx, *dryRun || config.GC.DryRun)
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(report); encErr != nil {
+			logger.Error("Failed to write report", zap.Error(encErr))
+		}
+	}
+	if err != nil {
+		logger.Fatal("Collection failed", zap.Error(err))
+	}
+}
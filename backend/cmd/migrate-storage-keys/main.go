@@ -0,0 +1,265 @@
+// Command migrate-storage-keys moves resume PDFs from the old name-based keys
+// ({userId}/resumes/{resumeName}/) to content-addressed keys
+// ({userId}/resumes/{resumeId}/{sha256}.pdf) and rewrites app.resumes and
+// app.resume_versions to match. It is safe to re-run: rows already on the new
+// layout are skipped, and an old object is only deleted once nothing references it.
+//
+// Two resumes that shared a name also shared an object, so both end up pointing at
+// a copy of whichever file was uploaded last; that data was already lost.
+//
+// It also queues a render for every resume whose public preview is still under the
+// old users/{userId}/ webp layout, which exposed the owner's user ID. The worker
+// writes the new renditions and deletes the old ones. Superseded versions still on
+// that layout have their renditions cleared and deleted; rolling back to one
+// renders it again.
+//
+// Usage: go run ./cmd/migrate-storage-keys [-dry-run]
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+
+	sqlc "main/db/sqlc"
+	"main/service/processing"
+	"main/service/spaces"
+	"main/utils"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "log the planned moves without copying, rewriting or deleting anything")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		fmt.Printf("Warning: Error loading .env file: %v", err)
+	}
+
+	logger := utils.Logger()
+	defer func() { _ = logger.Sync() }()
+
+	config, err := utils.LoadConfig()
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, os.Getenv("DATABASE_URL"))
+	if err != nil {
+		logger.Fatal("Failed to create connection pool", zap.Error(err))
+	}
+	defer pool.Close()
+
+	resumeStore, err := spaces.OpenResumeStore(ctx, config, logger)
+	if err != nil {
+		logger.Fatal("Failed to open resume bucket", zap.Error(err))
+	}
+	resumeBucket := spaces.NewResumeBucket(resumeStore, config.Resume.PresignTTL, logger)
+
+	webpStore, err := spaces.OpenWebpStore(ctx, config, logger)
+	if err != nil {
+		logger.Fatal("Failed to open webp bucket", zap.Error(err))
+	}
+	webpBucket := spaces.NewWebpBucket(webpStore, config.Webp.CDNBaseURL, logger)
+
+	db := sqlc.New(pool)
+	m := &migrator{pool: pool, db: db, bucket: resumeBucket, webpBucket: webpBucket, dryRun: *dryRun, log: logger}
+	failed, err := m.run(ctx)
+	if err != nil {
+		logger.Fatal("Migration failed", zap.Error(err))
+	}
+	rerendered, err := m.rerenderLegacy(ctx, processing.NewProcessingService(db, config.Processing.MaxAttempts))
+	if err != nil {
+		logger.Fatal("Queueing renders failed", zap.Error(err))
+	}
+	logger.Info("Queued renders for legacy previews", zap.Int("resumes", rerendered), zap.Bool("dry_run", *dryRun))
+	cleared, err := m.clearLegacyVersions(ctx)
+	if err != nil {
+		logger.Fatal("Clearing legacy version renditions failed", zap.Error(err))
+	}
+	logger.Info("Cleared legacy renditions of older versions", zap.Int("prefixes", cleared), zap.Bool("dry_run", *dryRun))
+	if failed > 0 {
+		logger.Error("Migration finished with failures; re-run to retry them", zap.Int("failed", failed))
+		os.Exit(1)
+	}
+}
+
+type migrator struct {
+	pool       *pgxpool.Pool
+	db         *sqlc.Queries
+	bucket     *spaces.ResumeBucket
+	webpBucket *spaces.WebpBucket
+	dryRun     bool
+	log        *zap.Logger
+}
+
+// run moves every legacy key and returns how many could not be moved.
+func (m *migrator) run(ctx context.Context) (int, error) {
+	rows, err := m.db.ListPdfStorageKeys(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list storage keys: %w", err)
+	}
+
+	var moved, failed int
+	oldKeys := make(map[string]struct{})
+	for _, row := range rows {
+		if !row.PdfStorageKey.Valid || spaces.IsContentKey(row.PdfStorageKey.String) {
+			continue
+		}
+		oldKey := row.PdfStorageKey.String
+
+		newKey, err := m.move(ctx, row)
+		if err != nil {
+			m.log.Error("Failed to move resume file",
+				zap.String("resume_id", row.ResumeID.String()),
+				zap.String("old_key", oldKey),
+				zap.Error(err))
+			failed++
+			continue
+		}
+
+		m.log.Info("Moved resume file",
+			zap.String("resume_id", row.ResumeID.String()),
+			zap.String("old_key", oldKey),
+			zap.String("new_key", newKey),
+			zap.Bool("dry_run", m.dryRun))
+		oldKeys[oldKey] = struct{}{}
+		moved++
+	}
+
+	deleted, err := m.deleteUnreferenced(ctx, oldKeys)
+	if err != nil {
+		return failed, err
+	}
+
+	m.log.Info("Storage key migration complete",
+		zap.Int("moved", moved),
+		zap.Int("failed", failed),
+		zap.Int("deleted", deleted),
+		zap.Bool("dry_run", m.dryRun))
+	return failed, nil
+}
+
+// move copies one resume's object to its content-addressed key and points the rows at it.
+func (m *migrator) move(ctx context.Context, row sqlc.ListPdfStorageKeysRow) (string, error) {
+	var buf bytes.Buffer
+	if _, err := m.bucket.StreamFileToWriter(ctx, row.PdfStorageKey.String, &buf); err != nil {
+		return "", fmt.Errorf("download: %w", err)
+	}
+
+	newKey := m.bucket.ContentKey(row.OwnerUserID.String(), row.ResumeID.String(), utils.ContentHash(buf.Bytes()))
+	if m.dryRun {
+		return newKey, nil
+	}
+
+	if err := m.bucket.PutPDF(ctx, newKey, bytes.NewReader(buf.Bytes())); err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := m.db.WithTx(tx)
+	newText := pgtype.Text{String: newKey, Valid: true}
+	if err := q.RewriteResumePdfStorageKey(ctx, sqlc.RewriteResumePdfStorageKeyParams{NewKey: newText, ResumeID: row.ResumeID, OldKey: row.PdfStorageKey}); err != nil {
+		return "", fmt.Errorf("rewrite resume: %w", err)
+	}
+	if err := q.RewriteResumeVersionPdfStorageKey(ctx, sqlc.RewriteResumeVersionPdfStorageKeyParams{NewKey: newText, ResumeID: row.ResumeID, OldKey: row.PdfStorageKey}); err != nil {
+		return "", fmt.Errorf("rewrite versions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+	return newKey, nil
+}
+
+// deleteUnreferenced removes the old objects no row points at any more. A key still
+// referenced (its other row failed to move) is kept for the next run.
+func (m *migrator) deleteUnreferenced(ctx context.Context, oldKeys map[string]struct{}) (int, error) {
+	if m.dryRun {
+		return 0, nil
+	}
+
+	var keys []string
+	for key := range oldKeys {
+		count, err := m.db.CountPdfStorageKeyReferences(ctx, pgtype.Text{String: key, Valid: true})
+		if err != nil {
+			return 0, fmt.Errorf("count references: %w", err)
+		}
+		if count == 0 {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if err := m.bucket.DeleteObjects(ctx, keys); err != nil {
+		return 0, fmt.Errorf("delete old objects: %w", err)
+	}
+	return len(keys), nil
+}
+
+// rerenderLegacy queues a render for every resume still previewed from the old webp
+// layout and returns how many there were.
+func (m *migrator) rerenderLegacy(ctx context.Context, processingService *processing.ProcessingService) (int, error) {
+	ids, err := m.db.ListLegacyRenditionResumeIDs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list legacy renditions: %w", err)
+	}
+	if m.dryRun {
+		return len(ids), nil
+	}
+	for _, id := range ids {
+		if err := processingService.EnqueueRender(ctx, id); err != nil {
+			return 0, fmt.Errorf("enqueue render for %s: %w", id.String(), err)
+		}
+	}
+	return len(ids), nil
+}
+
+// clearLegacyVersions drops old-layout renditions from superseded versions and
+// deletes the ones nothing references any more, returning how many prefixes were
+// cleared. A prefix whose delete fails is logged; nothing references it any more,
+// so the orphan collector (GC_ENABLED) removes it.
+func (m *migrator) clearLegacyVersions(ctx context.Context) (int, error) {
+	if m.dryRun {
+		return 0, nil
+	}
+
+	prefixes, err := m.db.ClearLegacyVersionRenditions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("clear legacy versions: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(prefixes))
+	for _, prefix := range prefixes {
+		if _, ok := seen[prefix]; ok {
+			continue
+		}
+		seen[prefix] = struct{}{}
+
+		refs, err := m.db.CountImageKeyPrefixReferences(ctx, pgtype.Text{String: prefix, Valid: true})
+		if err != nil {
+			return 0, fmt.Errorf("count references: %w", err)
+		}
+		if refs > 0 {
+			continue
+		}
+		if err := m.webpBucket.DeletePrefix(ctx, prefix); err != nil {
+			m.log.Error("Failed to delete legacy renditions", zap.String("prefix", prefix), zap.Error(err))
+		}
+	}
+	return len(seen), nil
+}
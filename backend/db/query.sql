@@ -1,13 +1,16 @@
 -- --------------------- START OF RESUME RELATED QUERIES ----------------------------------------
 
 -- Allocate a free slot (1..3) for an owner -------------------------------
+-- Slots reserved by a pending direct upload count as taken.
 -- name: FindFreeSlotForOwner :one
 with slots as (select unnest(array[1,2,3])::smallint as slot)
 select s.slot
 from slots s
 left join app.resumes r
   on r.owner_user_id = $1 and r.slot = s.slot
-where r.id is null
+left join app.upload_intents u
+  on u.owner_user_id = $1 and u.slot = s.slot and u.expires_at > now()
+where r.id is null and u.id is null
 order by s.slot
 limit 1;
 
@@ -16,11 +19,11 @@ limit 1;
 insert into app.resumes (
   owner_user_id, slot, name, industry, yoe_bucket,
   pdf_storage_key, pdf_size_bytes, pdf_mime,
-  image_key_prefix, page_count, image_ready
+  image_key_prefix, page_count, image_ready, hide_companies, id
 ) values (
   $1, $2, $3, $4, $5,
   $6, $7, coalesce($8, 'application/pdf'),
-  $9, coalesce($10, 1), coalesce($11, false)
+  $9, coalesce($10, 1), coalesce($11, false), $12, $13
 )
 returning *;
 
@@ -71,6 +74,47 @@ set image_key_prefix = $3,
 where id = $1 and owner_user_id = $2
 returning *;
 
+-- name: UpdateResumeHideCompanies :one
+update app.resumes
+set hide_companies = $3
+where id = $1 and owner_user_id = $2
+returning *;
+
+-- Every change is announced on the resume_processing channel (payload: resume id)
+-- so status streams on any backend instance can push it.
+-- name: SetResumeProcessingState :exec
+with updated as (
+  update app.resumes
+  set processing_state = @processing_state,
+      processing_error = @processing_error
+  where id = @id
+  returning id
+)
+select pg_notify('resume_processing', id::text)
+from updated;
+
+-- name: GetResumeProcessingStatusForOwner :one
+select id, processing_state, processing_error, image_ready, image_key_prefix, image_manifest
+from app.resumes
+where id = $1 and owner_user_id = $2;
+
+-- Swap in a freshly rendered preview; returns the previous prefix so it can be cleaned up.
+-- Only applies while the resume still has the PDF and options that were rendered;
+-- a render that raced a new upload, version or setting change affects no rows.
+-- name: UpdateResumeRenditions :one
+update app.resumes r
+set image_key_prefix = @image_key_prefix,
+    image_manifest = @image_manifest,
+    image_ready = true
+from (
+  select id, image_key_prefix
+  from app.resumes
+  where id = @id and pdf_storage_key = @pdf_storage_key and hide_companies = @hide_companies
+  for update
+) old
+where r.id = old.id
+returning old.image_key_prefix as previous_image_key_prefix;
+
 -- name: SetResumeInFlight :exec
 update app.resumes
 set in_flight = $3
@@ -88,4 +132,690 @@ from app.resumes
 where owner_user_id = $1
 order by slot;
 
--- --------------------- END OF RESUME RELATED QUERIES ----------------------------------------
\ No newline at end of file
+-- --------------------- END OF RESUME RELATED QUERIES ----------------------------------------
+
+-- --------------------- START OF MATCHMAKING RELATED QUERIES ----------------------------------------
+
+-- Pick a seed (least recently matched) and its nearest neighbours by Elo on
+-- either side. Rows are locked with SKIP LOCKED so concurrent callers never
+-- receive the same resume. The voter's own resumes are never candidates; guests
+-- pass a null owner and see every resume.
+-- name: PairCandidates :one
+with seed as (
+  select id, current_elo_int
+  from app.resumes
+  where industry = @industry and yoe_bucket = @yoe_bucket and image_ready and not in_flight
+    and owner_user_id is distinct from @exclude_owner_user_id::uuid
+  order by coalesce(last_matched_at, '-infinity') asc
+  limit 1
+  for update skip locked
+)
+, down as (
+  select id, current_elo_int
+  from app.resumes
+  where industry = @industry and yoe_bucket = @yoe_bucket and image_ready and not in_flight
+    and owner_user_id is distinct from @exclude_owner_user_id::uuid
+    and id <> (select id from seed)
+    and current_elo_int <= (select current_elo_int from seed)
+  order by current_elo_int desc, id
+  limit 1
+  for update skip locked
+)
+, up as (
+  select id, current_elo_int
+  from app.resumes
+  where industry = @industry and yoe_bucket = @yoe_bucket and image_ready and not in_flight
+    and owner_user_id is distinct from @exclude_owner_user_id::uuid
+    and id <> (select id from seed)
+    and current_elo_int >= (select current_elo_int from seed)
+  order by current_elo_int asc, id
+  limit 1
+  for update skip locked
+)
+select
+  seed.id as seed_id,
+  seed.current_elo_int as seed_elo,
+  down.id as down_id,
+  down.current_elo_int as down_elo,
+  up.id as up_id,
+  up.current_elo_int as up_elo
+from seed
+left join down on true
+left join up on true;
+
+-- name: SetResumeInFlightByID :exec
+update app.resumes
+set in_flight = $2
+where id = $1;
+
+-- name: CreateMatch :one
+insert into app.matches (
+  resume_a_id, resume_b_id, industry, yoe_bucket, issued_to_user_id, issued_to_guest_session_id
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning *;
+
+-- name: GetMatchByID :one
+select *
+from app.matches
+where id = $1;
+
+-- name: GetMatchByIDForUpdate :one
+select *
+from app.matches
+where id = $1
+for update;
+
+-- name: MatchHasResumeOwnedBy :one
+select exists (
+  select 1
+  from app.resumes
+  where (id = @resume_a_id or id = @resume_b_id) and owner_user_id = @owner_user_id
+);
+
+-- Lock both resumes in ID order so concurrent resolutions cannot deadlock.
+-- name: LockResumePairForUpdate :many
+select id, current_elo_int, battles_count
+from app.resumes
+where id = @resume_a_id or id = @resume_b_id
+order by id
+for update;
+
+-- name: ApplyResumeMatchResult :exec
+update app.resumes
+set current_elo_int = current_elo_int + @delta::integer,
+    battles_count = battles_count + 1,
+    last_matched_at = now(),
+    in_flight = false
+where id = @resume_id;
+
+-- Only matches still in 'created' can be resolved or cancelled, so a second
+-- call affects no rows instead of applying the result twice.
+-- name: ResolveMatch :one
+update app.matches
+set resolved_at = now(),
+    winner_resume_id = $2,
+    loser_resume_id = $3,
+    decided_by_user_id = $4,
+    k_factor_used = $5,
+    delta_a = $6,
+    delta_b = $7,
+    outcome = $8,
+    decided_by_guest_session_id = $9,
+    state = 'resolved'
+where id = $1 and state = 'created'
+returning *;
+
+-- name: CancelMatch :one
+update app.matches
+set resolved_at = now(),
+    outcome = $2,
+    decided_by_user_id = $3,
+    decided_by_guest_session_id = $4,
+    state = 'cancelled'
+where id = $1 and state = 'created'
+returning *;
+
+-- Cancel a batch of matches nobody voted on. SKIP LOCKED leaves matches that
+-- are being resolved right now alone.
+-- name: CancelStaleMatches :many
+update app.matches
+set resolved_at = now(),
+    state = 'cancelled'
+where id in (
+  select id
+  from app.matches
+  where state = 'created' and created_at < @cutoff
+  order by created_at
+  limit @batch_size
+  for update skip locked
+)
+returning resume_a_id, resume_b_id;
+
+-- name: ReleaseResumesInFlight :execrows
+update app.resumes
+set in_flight = false
+where id = any(@resume_ids::uuid[]);
+
+-- name: ListMatchesByResume :many
+select *
+from app.matches
+where resume_a_id = @resume_id or resume_b_id = @resume_id
+order by created_at desc, id
+limit @row_limit offset @row_offset;
+
+-- --------------------- END OF MATCHMAKING RELATED QUERIES ----------------------------------------
+
+
+-- --------------------- START OF LEADERBOARD RELATED QUERIES ----------------------------------------
+
+-- Keyset pagination on (current_elo_int desc, id). Pass a null cursor for the first page.
+-- name: ListLeaderboard :many
+select id, current_elo_int, battles_count, image_key_prefix, image_manifest
+from app.resumes
+where industry = @industry and yoe_bucket = @yoe_bucket and image_ready
+  and (
+    sqlc.narg(cursor_elo)::integer is null
+    or current_elo_int < sqlc.narg(cursor_elo)::integer
+    or (current_elo_int = sqlc.narg(cursor_elo)::integer and id > sqlc.narg(cursor_id)::uuid)
+  )
+order by current_elo_int desc, id
+limit @row_limit;
+
+-- Same shape and ordering as ListLeaderboard, read from the materialized view.
+-- name: ListLeaderboardMaterialized :many
+select resume_id, current_elo_int, battles_count, image_key_prefix, image_manifest
+from app.leaderboard
+where industry = @industry and yoe_bucket = @yoe_bucket
+  and (
+    sqlc.narg(cursor_elo)::integer is null
+    or current_elo_int < sqlc.narg(cursor_elo)::integer
+    or (current_elo_int = sqlc.narg(cursor_elo)::integer and resume_id > sqlc.narg(cursor_id)::uuid)
+  )
+order by current_elo_int desc, resume_id
+limit @row_limit;
+
+-- name: IsLeaderboardPopulated :one
+select ispopulated
+from pg_matviews
+where schemaname = 'app' and matviewname = 'leaderboard';
+
+-- Blocks readers; only used for the first population.
+-- name: RefreshLeaderboard :exec
+refresh materialized view app.leaderboard;
+
+-- name: RefreshLeaderboardConcurrently :exec
+refresh materialized view concurrently app.leaderboard;
+
+-- name: DeferRenditionDeletion :exec
+insert into app.superseded_renditions (image_key_prefix)
+values ($1)
+on conflict (image_key_prefix) do nothing;
+
+-- Renditions superseded before a refresh that started at @refreshed_from, which
+-- no longer lists them.
+-- name: ClaimSupersededRenditions :many
+delete from app.superseded_renditions
+where superseded_at < @refreshed_from
+returning image_key_prefix;
+
+-- --------------------- END OF LEADERBOARD RELATED QUERIES ----------------------------------------
+
+
+-- --------------------- START OF FEEDBACK RELATED QUERIES ----------------------------------------
+
+-- name: CreateFeedback :one
+insert into app.feedback (
+  match_id, target_resume_id, author_user_id, visibility, text, tags
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning *;
+
+-- name: ListFeedbackForResume :many
+select *
+from app.feedback
+where target_resume_id = $1
+order by created_at desc, id;
+
+-- name: CountFeedbackTagsForResume :many
+select t.tag::text as tag, count(*) as count
+from app.feedback f
+cross join lateral unnest(f.tags) as t(tag)
+where f.target_resume_id = $1
+group by t.tag
+order by count(*) desc, t.tag;
+
+-- --------------------- END OF FEEDBACK RELATED QUERIES ----------------------------------------
+
+
+-- --------------------- START OF RESUME JOB RELATED QUERIES ----------------------------------------
+
+-- Returns no rows when the same job is already waiting to run. A new job marks the
+-- resume queued in the same statement (announced like SetResumeProcessingState), so
+-- a worker that claims it can't have its progress overwritten afterwards.
+-- name: EnqueueResumeJob :one
+with job as (
+  insert into app.resume_jobs (resume_id, kind, max_attempts)
+  values ($1, $2, $3)
+  on conflict (resume_id, kind) where state = 'queued' do nothing
+  returning *
+)
+, queued as (
+  update app.resumes
+  set processing_state = 'queued',
+      processing_error = null
+  where id = (select resume_id from job)
+  returning id
+)
+select job.*
+from job
+left join (select pg_notify('resume_processing', id::text) from queued) notified on true;
+
+-- A resume with a job of the same kind still running is skipped, so two renders of
+-- one resume never overlap.
+-- name: ClaimResumeJobs :many
+update app.resume_jobs
+set state = 'running',
+    attempts = attempts + 1,
+    locked_at = now()
+where id in (
+  select j.id
+  from app.resume_jobs j
+  where j.state = 'queued' and j.run_at <= now()
+    and not exists (
+      select 1 from app.resume_jobs r
+      where r.resume_id = j.resume_id and r.kind = j.kind and r.state = 'running'
+    )
+  order by j.run_at, j.id
+  limit @batch_size
+  for update skip locked
+)
+returning *;
+
+-- name: CompleteResumeJob :exec
+update app.resume_jobs
+set state = 'succeeded',
+    locked_at = null,
+    last_error = null,
+    finished_at = now()
+where id = $1;
+
+-- A job queued for the same resume since this one was claimed already covers the
+-- retry, so this one is closed as failed instead of tripping resume_jobs_pending_unique.
+-- name: RetryResumeJob :exec
+update app.resume_jobs j
+set state = case when s.superseded then 'failed' else 'queued' end,
+    locked_at = null,
+    run_at = @run_at,
+    last_error = @last_error,
+    finished_at = case when s.superseded then now() end
+from (
+  select exists (
+    select 1 from app.resume_jobs q
+    where q.resume_id = r.resume_id and q.kind = r.kind and q.state = 'queued'
+  ) as superseded
+  from app.resume_jobs r
+  where r.id = @id
+) s
+where j.id = @id;
+
+-- name: FailResumeJob :exec
+update app.resume_jobs
+set state = 'failed',
+    locked_at = null,
+    last_error = @last_error,
+    finished_at = now()
+where id = @id;
+
+-- Jobs whose worker died mid-run. The newest per resume and kind is requeued; the
+-- rest, and any with a queued job already waiting, are closed as failed so
+-- resume_jobs_pending_unique holds.
+-- name: RequeueStuckResumeJobs :execrows
+with stuck as (
+  select s.id,
+    row_number() over (partition by s.resume_id, s.kind order by s.created_at desc, s.id desc) > 1
+      or exists (
+        select 1 from app.resume_jobs q
+        where q.resume_id = s.resume_id and q.kind = s.kind and q.state = 'queued'
+      ) as superseded
+  from (
+    select id, resume_id, kind, created_at
+    from app.resume_jobs
+    where state = 'running' and locked_at < @cutoff
+    for update skip locked
+  ) s
+)
+update app.resume_jobs j
+set state = case when stuck.superseded then 'failed' else 'queued' end,
+    locked_at = null,
+    run_at = now(),
+    last_error = case when stuck.superseded then 'superseded by a newer job' else j.last_error end,
+    finished_at = case when stuck.superseded then now() end
+from stuck
+where j.id = stuck.id;
+
+-- --------------------- END OF RESUME JOB RELATED QUERIES ----------------------------------------
+
+
+-- --------------------- START OF RESUME VERSION RELATED QUERIES ----------------------------------------
+
+-- Record the live version (files and current rating) in app.resume_versions.
+-- Run when a resume is created and again just before it is superseded.
+-- name: SnapshotCurrentResumeVersion :exec
+insert into app.resume_versions (
+  resume_id, version, pdf_storage_key, pdf_size_bytes, page_count,
+  image_key_prefix, image_manifest, final_elo_int, final_battles_count
+)
+select id, current_version, pdf_storage_key, pdf_size_bytes, page_count,
+  image_key_prefix, image_manifest, current_elo_int, battles_count
+from app.resumes
+where id = $1
+on conflict (resume_id, version) do update
+set image_key_prefix = excluded.image_key_prefix,
+    image_manifest = excluded.image_manifest,
+    -- Only SetCurrentVersionRenditions knows what new renditions were made with.
+    rendered_hide_companies = case
+      when excluded.image_key_prefix is not distinct from app.resume_versions.image_key_prefix
+      then app.resume_versions.rendered_hide_companies
+    end,
+    final_elo_int = excluded.final_elo_int,
+    final_battles_count = excluded.final_battles_count;
+
+-- name: GetResumeByIDForOwnerForUpdate :one
+select *
+from app.resumes
+where id = $1 and owner_user_id = $2
+for update;
+
+-- name: NextResumeVersion :one
+select (coalesce(max(version), 0) + 1)::integer as next_version
+from app.resume_versions
+where resume_id = $1;
+
+-- name: CreateResumeVersion :one
+insert into app.resume_versions (
+  resume_id, version, pdf_storage_key, pdf_size_bytes, page_count
+) values (
+  $1, $2, $3, $4, $5
+)
+returning *;
+
+-- name: GetResumeVersion :one
+select *
+from app.resume_versions
+where resume_id = $1 and version = $2;
+
+-- name: ListResumeVersions :many
+select *
+from app.resume_versions
+where resume_id = $1
+order by version desc;
+
+-- Make a version live: copy its files onto the resume and set the rating.
+-- name: SetResumeCurrentVersion :one
+update app.resumes
+set current_version = @version,
+    pdf_storage_key = @pdf_storage_key,
+    pdf_size_bytes = @pdf_size_bytes,
+    page_count = @page_count,
+    image_key_prefix = @image_key_prefix,
+    image_manifest = @image_manifest,
+    image_ready = @image_ready,
+    processing_state = @processing_state,
+    processing_error = null,
+    current_elo_int = @current_elo_int,
+    battles_count = @battles_count
+where id = @id
+returning *;
+
+-- Keep the live version's row pointing at its latest renditions, rendered with
+-- rendered_hide_companies.
+-- name: SetCurrentVersionRenditions :exec
+update app.resume_versions v
+set image_key_prefix = r.image_key_prefix,
+    image_manifest = r.image_manifest,
+    rendered_hide_companies = @rendered_hide_companies
+from app.resumes r
+where r.id = @id and v.resume_id = r.id and v.version = r.current_version;
+
+-- name: CountResumeVersionsByImagePrefix :one
+select count(*)
+from app.resume_versions
+where image_key_prefix = $1;
+
+-- --------------------- END OF RESUME VERSION RELATED QUERIES ----------------------------------------
+
+
+-- --------------------- START OF STORAGE KEY RELATED QUERIES ----------------------------------------
+
+-- Every PDF key referenced by a resume or one of its versions.
+-- name: ListPdfStorageKeys :many
+select r.id as resume_id, r.owner_user_id, r.pdf_storage_key
+from app.resumes r
+where r.pdf_storage_key is not null
+union
+select v.resume_id, r.owner_user_id, v.pdf_storage_key
+from app.resume_versions v
+join app.resumes r on r.id = v.resume_id
+where v.pdf_storage_key is not null
+order by resume_id, pdf_storage_key;
+
+-- name: RewriteResumePdfStorageKey :exec
+update app.resumes
+set pdf_storage_key = @new_key
+where id = @resume_id and pdf_storage_key = @old_key;
+
+-- name: RewriteResumeVersionPdfStorageKey :exec
+update app.resume_versions
+set pdf_storage_key = @new_key
+where resume_id = @resume_id and pdf_storage_key = @old_key;
+
+-- Every rendition prefix referenced by a resume or one of its versions, or possibly
+-- still listed by the materialized leaderboard.
+-- name: ListImageKeyPrefixes :many
+select image_key_prefix::text as image_key_prefix
+from app.resumes
+where image_key_prefix is not null and image_key_prefix <> ''
+union
+select image_key_prefix::text
+from app.resume_versions
+where image_key_prefix is not null and image_key_prefix <> ''
+union
+select image_key_prefix
+from app.superseded_renditions;
+
+-- Resumes whose current preview is still under the old users/{userId}/ webp layout.
+-- name: ListLegacyRenditionResumeIDs :many
+select id
+from app.resumes
+where image_key_prefix like 'users/%'
+order by id;
+
+-- Drop old-layout renditions from superseded versions, so rolling back to one
+-- re-renders it. Returns the prefixes that were cleared.
+-- name: ClearLegacyVersionRenditions :many
+update app.resume_versions v
+set image_key_prefix = null,
+    image_manifest = null,
+    rendered_hide_companies = null
+from app.resume_versions prev, app.resumes r
+where prev.id = v.id
+  and r.id = v.resume_id
+  and v.version <> r.current_version
+  and prev.image_key_prefix like 'users/%'
+returning prev.image_key_prefix::text as image_key_prefix;
+
+-- name: CountImageKeyPrefixReferences :one
+select (
+  (select count(*) from app.resumes where image_key_prefix = @prefix) +
+  (select count(*) from app.resume_versions where image_key_prefix = @prefix)
+)::bigint as count;
+
+-- name: CountPdfStorageKeyReferences :one
+select (
+  (select count(*) from app.resumes where pdf_storage_key = @key) +
+  (select count(*) from app.resume_versions where pdf_storage_key = @key)
+)::bigint as count;
+
+-- --------------------- END OF STORAGE KEY RELATED QUERIES ----------------------------------------
+
+
+-- --------------------- START OF RESUME TOMBSTONE RELATED QUERIES ----------------------------------------
+
+-- Every PDF key a resume or any of its versions points at.
+-- name: ListResumePdfKeys :many
+select pdf_storage_key::text as pdf_storage_key
+from app.resumes
+where id = $1 and pdf_storage_key is not null
+union
+select pdf_storage_key::text
+from app.resume_versions
+where resume_id = $1 and pdf_storage_key is not null;
+
+-- name: CreateResumeTombstone :one
+insert into app.resume_tombstones (
+  resume_id, pdf_keys, pdf_prefix, image_prefix, legacy_image_prefix, run_at
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning *;
+
+-- Lease due tombstones by pushing run_at past the time a purge can take.
+-- name: ClaimResumeTombstones :many
+update app.resume_tombstones
+set run_at = @lease_until
+where id in (
+  select id
+  from app.resume_tombstones
+  where run_at <= now()
+  order by run_at, id
+  limit @batch_size
+  for update skip locked
+)
+returning *;
+
+-- name: RetryResumeTombstone :exec
+update app.resume_tombstones
+set attempts = attempts + 1,
+    run_at = @run_at,
+    last_error = @last_error
+where id = @id;
+
+-- name: DeleteResumeTombstone :exec
+delete from app.resume_tombstones
+where id = $1;
+
+-- --------------------- END OF RESUME TOMBSTONE RELATED QUERIES ----------------------------------------
+
+-- --------------------- START OF VOTE VIOLATION RELATED QUERIES ----------------------------------------
+
+-- name: CreateVoteViolation :exec
+insert into app.vote_violations (match_id, user_id, guest_session_id, reason)
+values ($1, $2, $3, $4);
+
+-- --------------------- END OF VOTE VIOLATION RELATED QUERIES ----------------------------------------
+
+-- --------------------- START OF GUEST SESSION RELATED QUERIES ----------------------------------------
+
+-- name: CreateGuestSession :one
+insert into app.guest_sessions (expires_at)
+values ($1)
+returning *;
+
+-- name: GetGuestSession :one
+select *
+from app.guest_sessions
+where id = $1;
+
+-- Only an unmerged session can be merged, so a replayed merge affects no rows.
+-- name: MarkGuestSessionMerged :one
+update app.guest_sessions
+set merged_into_user_id = @user_id,
+    merged_at = now()
+where id = @id and merged_into_user_id is null
+returning *;
+
+-- Audits votes the session cast on the user's own resumes, which the vote endpoint
+-- can't catch while the voter is anonymous. AttributeGuestVotesToUser leaves them out.
+-- name: RecordGuestSelfVotes :execrows
+insert into app.vote_violations (match_id, user_id, guest_session_id, reason)
+select m.id, @user_id::uuid, m.decided_by_guest_session_id, 'self_vote'
+from app.matches m
+where m.decided_by_guest_session_id = @guest_session_id and m.decided_by_user_id is null
+  and exists (
+    select 1
+    from app.resumes r
+    where r.id in (m.resume_a_id, m.resume_b_id) and r.owner_user_id = @user_id::uuid
+  );
+
+-- Undoes the rating changes of votes the session cast on the user's own resumes,
+-- for both sides of each match, and voids those matches. Returns how many matches
+-- were reversed.
+-- name: ReverseGuestSelfVotes :one
+with reversed as (
+  update app.matches m
+  set state = 'cancelled'
+  where m.decided_by_guest_session_id = @guest_session_id and m.decided_by_user_id is null
+    and m.state = 'resolved'
+    and exists (
+      select 1
+      from app.resumes r
+      where r.id in (m.resume_a_id, m.resume_b_id) and r.owner_user_id = @user_id::uuid
+    )
+  returning m.resume_a_id, m.resume_b_id, m.delta_a, m.delta_b
+)
+, deltas as (
+  select resume_id, sum(delta)::integer as delta, count(*)::integer as battles
+  from (
+    select resume_a_id as resume_id, delta_a as delta from reversed
+    union all
+    select resume_b_id, delta_b from reversed
+  ) d
+  group by resume_id
+)
+, restored as (
+  update app.resumes r
+  set current_elo_int = r.current_elo_int - d.delta,
+      battles_count = greatest(r.battles_count - d.battles, 0)
+  from deltas d
+  where r.id = d.resume_id
+  returning r.id
+)
+select count(*) as reversed
+from reversed;
+
+-- Attribution only: ratings already moved by the guest's (lower) weight stay as they are.
+-- name: AttributeGuestVotesToUser :execrows
+update app.matches m
+set decided_by_user_id = @user_id
+where m.decided_by_guest_session_id = @guest_session_id and m.decided_by_user_id is null
+  and not exists (
+    select 1
+    from app.resumes r
+    where r.id in (m.resume_a_id, m.resume_b_id) and r.owner_user_id = @user_id
+  );
+
+-- --------------------- END OF GUEST SESSION RELATED QUERIES ----------------------------------------
+
+-- --------------------- START OF UPLOAD INTENT RELATED QUERIES ----------------------------------------
+
+-- Frees slots held by abandoned uploads so the unique (owner, slot) index doesn't trip.
+-- Returns the staged keys so the caller can delete whatever was uploaded to them.
+-- name: DeleteExpiredUploadIntentsForOwner :many
+delete from app.upload_intents
+where owner_user_id = $1 and expires_at <= now()
+returning object_key;
+
+-- name: CreateUploadIntent :one
+insert into app.upload_intents (
+  id, owner_user_id, slot, name, industry, yoe_bucket, hide_companies, object_key, expires_at
+) values (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+returning *;
+
+-- name: GetUploadIntentForOwner :one
+select *
+from app.upload_intents
+where id = $1 and owner_user_id = $2;
+
+-- Locks the intent so only one finalize turns it into a resume.
+-- name: LockUploadIntentForOwner :one
+select *
+from app.upload_intents
+where id = $1 and owner_user_id = $2
+for update;
+
+-- name: DeleteUploadIntent :exec
+delete from app.upload_intents
+where id = $1;
+
+-- Staged keys that can still be finalized; the orphan sweep leaves these alone.
+-- name: ListUploadIntentKeys :many
+select object_key
+from app.upload_intents
+where expires_at > now();
+
+-- --------------------- END OF UPLOAD INTENT RELATED QUERIES ----------------------------------------
@@ -24,6 +24,232 @@ create table if not exists app.resumes (
   page_count smallint not null default 1 check (page_count between 1 and 2),
   image_ready boolean not null default false,
   slot smallint not null check (slot between 1 and 3),
+  -- Mask employer names in the public previews.
+  hide_companies boolean not null default false,
+  -- Progress of the background render: queued, rendering, redacting, ready or failed.
+  processing_state text not null default 'queued',
+  processing_error text,
+  -- Rendition manifest (pages x widths) of the preview under image_key_prefix.
+  image_manifest jsonb,
+  -- Version in app.resume_versions whose files are live.
+  current_version integer not null default 1,
   constraint resumes_industry_nonempty check (length(trim(industry)) > 0),
-  constraint resumes_yoe_nonempty check (length(trim(yoe_bucket)) > 0)
-);
\ No newline at end of file
+  constraint resumes_yoe_nonempty check (length(trim(yoe_bucket)) > 0),
+  constraint resumes_processing_state_valid check (processing_state in ('queued', 'rendering', 'redacting', 'ready', 'failed'))
+);
+
+-- Anonymous voters. The backend signs a token carrying the id; once the guest signs
+-- in, the session is merged and its votes are attributed to the account.
+create table if not exists app.guest_sessions (
+  id uuid primary key default gen_random_uuid(),
+  created_at timestamptz not null default now(),
+  expires_at timestamptz not null,
+  merged_into_user_id uuid references auth.users(id) on delete set null,
+  merged_at timestamptz
+);
+
+create table if not exists app.matches (
+  id uuid primary key default gen_random_uuid(),
+  resume_a_id uuid not null references app.resumes(id) on delete cascade,
+  resume_b_id uuid not null references app.resumes(id) on delete cascade,
+  industry text not null,
+  yoe_bucket text not null,
+  created_at timestamptz not null default now(),
+  -- Voter the pair was served to; only they can resolve it. Exactly one is set.
+  issued_to_user_id uuid references auth.users(id) on delete cascade,
+  issued_to_guest_session_id uuid references app.guest_sessions(id) on delete cascade,
+  resolved_at timestamptz,
+  winner_resume_id uuid references app.resumes(id) on delete set null,
+  loser_resume_id uuid references app.resumes(id) on delete set null,
+  decided_by_user_id uuid references auth.users(id) on delete set null,
+  -- Set instead of decided_by_user_id when a guest voted.
+  decided_by_guest_session_id uuid references app.guest_sessions(id) on delete set null,
+  k_factor_used integer,
+  delta_a integer,
+  delta_b integer,
+  state text not null default 'created',
+  outcome text,
+  constraint matches_distinct_resumes check (resume_a_id <> resume_b_id),
+  constraint matches_issued_to_one check (num_nonnulls(issued_to_user_id, issued_to_guest_session_id) = 1),
+  constraint matches_state_valid check (state in ('created', 'resolved', 'cancelled')),
+  constraint matches_outcome_valid check (outcome in ('a', 'b', 'draw', 'skip'))
+);
+
+-- Candidates available for pairing in a bucket, ordered by Elo
+create index if not exists resumes_bucket_elo_ready_idx
+  on app.resumes (industry, yoe_bucket, current_elo_int, id)
+  where image_ready and not in_flight;
+
+-- Leaderboard keyset pagination
+create index if not exists resumes_leaderboard_idx
+  on app.resumes (industry, yoe_bucket, current_elo_int desc, id)
+  where image_ready;
+
+-- Prevent duplicate in-flight exact pairs
+create unique index if not exists matches_open_pair_unique
+  on app.matches (least(resume_a_id, resume_b_id), greatest(resume_a_id, resume_b_id))
+  where state = 'created';
+
+create index if not exists matches_resume_a_idx on app.matches (resume_a_id, created_at desc);
+create index if not exists matches_resume_b_idx on app.matches (resume_b_id, created_at desc);
+
+create index if not exists matches_decided_by_guest_session_idx
+  on app.matches (decided_by_guest_session_id) where decided_by_guest_session_id is not null;
+
+-- Votes refused by the match service: self-votes, votes on a match served to someone
+-- else, and replays of an already decided match.
+create table if not exists app.vote_violations (
+  id uuid primary key default gen_random_uuid(),
+  match_id uuid references app.matches(id) on delete set null,
+  user_id uuid references auth.users(id) on delete set null,
+  guest_session_id uuid references app.guest_sessions(id) on delete set null,
+  reason text not null,
+  created_at timestamptz not null default now(),
+  constraint vote_violations_reason_valid check (reason in ('self_vote', 'not_issued_to_voter', 'replay'))
+);
+
+create index if not exists vote_violations_created_idx on app.vote_violations (created_at desc);
+
+-- Open matches by age, for the stale match reaper
+create index if not exists matches_open_created_idx
+  on app.matches (created_at)
+  where state = 'created';
+
+-- Snapshot of the leaderboard, refreshed by the backend on an interval or after
+-- a batch of resolved matches. Starts empty; the first refresh populates it.
+create materialized view if not exists app.leaderboard as
+select industry, yoe_bucket, id as resume_id, current_elo_int, battles_count, image_key_prefix, image_manifest
+from app.resumes
+where image_ready
+with no data;
+
+-- REFRESH ... CONCURRENTLY requires a unique index
+create unique index if not exists leaderboard_resume_id_idx
+  on app.leaderboard (resume_id);
+
+create index if not exists leaderboard_bucket_rank_idx
+  on app.leaderboard (industry, yoe_bucket, current_elo_int desc, resume_id);
+
+-- Renditions replaced by a re-render while app.leaderboard may still list them. The
+-- refresher deletes them once a refresh that started after superseded_at succeeds.
+create table if not exists app.superseded_renditions (
+  image_key_prefix text primary key,
+  superseded_at timestamptz not null default now()
+);
+
+create table if not exists app.feedback (
+  id uuid primary key default gen_random_uuid(),
+  match_id uuid not null references app.matches(id) on delete cascade,
+  target_resume_id uuid not null references app.resumes(id) on delete cascade,
+  author_user_id uuid references auth.users(id) on delete set null,
+  visibility text not null default 'owner',
+  text text not null default '',
+  tags text[] not null default '{}',
+  created_at timestamptz not null default now(),
+  constraint feedback_visibility_valid check (visibility in ('owner', 'public')),
+  constraint feedback_text_length check (length(text) <= 2000),
+  constraint feedback_nonempty check (length(trim(text)) > 0 or cardinality(tags) > 0)
+);
+
+-- One piece of feedback per voter per resume per match
+create unique index if not exists feedback_author_target_unique
+  on app.feedback (match_id, target_resume_id, author_user_id);
+
+create index if not exists feedback_target_created_idx
+  on app.feedback (target_resume_id, created_at desc);
+
+-- Feedback tags search
+create index if not exists feedback_tags_gin on app.feedback using gin (tags);
+
+-- Background work on uploaded resumes (render, redact, thumbnails), claimed by the
+-- backend's worker pool with FOR UPDATE SKIP LOCKED.
+create table if not exists app.resume_jobs (
+  id uuid primary key default gen_random_uuid(),
+  resume_id uuid not null references app.resumes(id) on delete cascade,
+  kind text not null,
+  state text not null default 'queued',
+  attempts integer not null default 0,
+  max_attempts integer not null default 5,
+  run_at timestamptz not null default now(),
+  locked_at timestamptz,
+  last_error text,
+  created_at timestamptz not null default now(),
+  finished_at timestamptz,
+  constraint resume_jobs_kind_valid check (kind in ('render')),
+  constraint resume_jobs_state_valid check (state in ('queued', 'running', 'succeeded', 'failed'))
+);
+
+-- At most one pending job of each kind per resume
+create unique index if not exists resume_jobs_pending_unique
+  on app.resume_jobs (resume_id, kind) where state = 'queued';
+
+create index if not exists resume_jobs_queued_run_at_idx
+  on app.resume_jobs (run_at) where state = 'queued';
+
+create index if not exists resume_jobs_running_locked_at_idx
+  on app.resume_jobs (locked_at) where state = 'running';
+
+-- Every revision uploaded into a resume slot, each with its own files. The live
+-- version is mirrored on app.resumes; final_* hold the rating when it was superseded.
+create table if not exists app.resume_versions (
+  id uuid primary key default gen_random_uuid(),
+  resume_id uuid not null references app.resumes(id) on delete cascade,
+  version integer not null,
+  pdf_storage_key text,
+  pdf_size_bytes bigint,
+  page_count smallint not null default 1 check (page_count between 1 and 2),
+  image_key_prefix text,
+  image_manifest jsonb,
+  final_elo_int integer,
+  final_battles_count integer,
+  created_at timestamptz not null default now(),
+  -- hide_companies setting image_key_prefix was rendered with; null when unknown.
+  -- Rolling back to renditions made with another setting re-renders them.
+  rendered_hide_companies boolean,
+  constraint resume_versions_version_positive check (version > 0)
+);
+
+create unique index if not exists resume_versions_resume_version_unique
+  on app.resume_versions (resume_id, version);
+
+create index if not exists resume_versions_image_key_prefix_idx
+  on app.resume_versions (image_key_prefix);
+
+-- Objects still to be removed for a deleted resume. Written in the same transaction
+-- that deletes the resume row, so a failed bucket call never leaves untracked orphans.
+create table if not exists app.resume_tombstones (
+  id uuid primary key default gen_random_uuid(),
+  resume_id uuid not null,
+  -- Keys outside pdf_prefix (pre-migration name-based keys).
+  pdf_keys text[] not null default '{}',
+  pdf_prefix text not null,
+  image_prefix text not null,
+  -- Renditions under the old users/{userId}/resumes/{resumeId}/ layout.
+  legacy_image_prefix text not null default '',
+  attempts integer not null default 0,
+  last_error text,
+  run_at timestamptz not null default now(),
+  created_at timestamptz not null default now()
+);
+
+create index if not exists resume_tombstones_run_at_idx
+  on app.resume_tombstones (run_at);
+
+-- Slots held for direct-to-bucket uploads between upload-intent and finalize. The
+-- id becomes the resume's id; expired rows no longer hold their slot.
+create table if not exists app.upload_intents (
+  id uuid primary key,
+  owner_user_id uuid not null references auth.users(id) on delete cascade,
+  slot smallint not null check (slot between 1 and 3),
+  name text not null,
+  industry text not null,
+  yoe_bucket text not null,
+  hide_companies boolean not null default false,
+  -- Staging key the browser uploads to.
+  object_key text not null,
+  expires_at timestamptz not null,
+  created_at timestamptz not null default now()
+);
+
+create unique index if not exists upload_intents_owner_slot_unique
+  on app.upload_intents (owner_user_id, slot);
@@ -5,27 +5,152 @@
 package db
 
 import (
+	"encoding/json"
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type AppResume struct {
+type AppFeedback struct {
 	ID             pgtype.UUID
-	Name           string
-	OwnerUserID    pgtype.UUID
+	MatchID        pgtype.UUID
+	TargetResumeID pgtype.UUID
+	AuthorUserID   pgtype.UUID
+	Visibility     string
+	Text           string
+	Tags           []string
+	CreatedAt      pgtype.Timestamptz
+}
+
+type AppGuestSession struct {
+	ID               pgtype.UUID
+	CreatedAt        pgtype.Timestamptz
+	ExpiresAt        pgtype.Timestamptz
+	MergedIntoUserID pgtype.UUID
+	MergedAt         pgtype.Timestamptz
+}
+
+type AppLeaderboard struct {
 	Industry       string
 	YoeBucket      string
+	ResumeID       pgtype.UUID
 	CurrentEloInt  int32
 	BattlesCount   int32
-	LastMatchedAt  pgtype.Timestamptz
-	InFlight       bool
-	CreatedAt      pgtype.Timestamptz
-	PdfStorageKey  pgtype.Text
-	PdfSizeBytes   pgtype.Int8
-	PdfMime        string
 	ImageKeyPrefix pgtype.Text
-	PageCount      int16
-	ImageReady     bool
-	Slot           int16
+}
+
+type AppMatch struct {
+	ID                      pgtype.UUID
+	ResumeAID               pgtype.UUID
+	ResumeBID               pgtype.UUID
+	Industry                string
+	YoeBucket               string
+	CreatedAt               pgtype.Timestamptz
+	IssuedToUserID          pgtype.UUID
+	IssuedToGuestSessionID  pgtype.UUID
+	ResolvedAt              pgtype.Timestamptz
+	WinnerResumeID          pgtype.UUID
+	LoserResumeID           pgtype.UUID
+	DecidedByUserID         pgtype.UUID
+	DecidedByGuestSessionID pgtype.UUID
+	KFactorUsed             pgtype.Int4
+	DeltaA                  pgtype.Int4
+	DeltaB                  pgtype.Int4
+	State                   string
+	Outcome                 pgtype.Text
+}
+
+type AppResume struct {
+	ID              pgtype.UUID
+	Name            string
+	OwnerUserID     pgtype.UUID
+	Industry        string
+	YoeBucket       string
+	CurrentEloInt   int32
+	BattlesCount    int32
+	LastMatchedAt   pgtype.Timestamptz
+	InFlight        bool
+	CreatedAt       pgtype.Timestamptz
+	PdfStorageKey   pgtype.Text
+	PdfSizeBytes    pgtype.Int8
+	PdfMime         string
+	ImageKeyPrefix  pgtype.Text
+	PageCount       int16
+	ImageReady      bool
+	Slot            int16
+	HideCompanies   bool
+	ProcessingState string
+	ProcessingError pgtype.Text
+	ImageManifest   json.RawMessage
+	CurrentVersion  int32
+}
+
+type AppResumeJob struct {
+	ID          pgtype.UUID
+	ResumeID    pgtype.UUID
+	Kind        string
+	State       string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       pgtype.Timestamptz
+	LockedAt    pgtype.Timestamptz
+	LastError   pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+	FinishedAt  pgtype.Timestamptz
+}
+
+type AppResumeTombstone struct {
+	ID                pgtype.UUID
+	ResumeID          pgtype.UUID
+	PdfKeys           []string
+	PdfPrefix         string
+	ImagePrefix       string
+	LegacyImagePrefix string
+	Attempts          int32
+	LastError         pgtype.Text
+	RunAt             pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+}
+
+type AppResumeVersion struct {
+	ID                pgtype.UUID
+	ResumeID          pgtype.UUID
+	Version           int32
+	PdfStorageKey     pgtype.Text
+	PdfSizeBytes      pgtype.Int8
+	PageCount         int16
+	ImageKeyPrefix    pgtype.Text
+	ImageManifest     json.RawMessage
+	FinalEloInt           pgtype.Int4
+	FinalBattlesCount     pgtype.Int4
+	CreatedAt             pgtype.Timestamptz
+	RenderedHideCompanies pgtype.Bool
+}
+
+type AppSupersededRendition struct {
+	ImageKeyPrefix string
+	SupersededAt   pgtype.Timestamptz
+}
+
+type AppUploadIntent struct {
+	ID            pgtype.UUID
+	OwnerUserID   pgtype.UUID
+	Slot          int16
+	Name          string
+	Industry      string
+	YoeBucket     string
+	HideCompanies bool
+	ObjectKey     string
+	ExpiresAt     pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+}
+
+type AppVoteViolation struct {
+	ID             pgtype.UUID
+	MatchID        pgtype.UUID
+	UserID         pgtype.UUID
+	GuestSessionID pgtype.UUID
+	Reason         string
+	CreatedAt      pgtype.Timestamptz
 }
 
 type AuthUser struct {
@@ -7,21 +7,625 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const applyResumeMatchResult = `-- name: ApplyResumeMatchResult :exec
+update app.resumes
+set current_elo_int = current_elo_int + $1::integer,
+    battles_count = battles_count + 1,
+    last_matched_at = now(),
+    in_flight = false
+where id = $2
+`
+
+type ApplyResumeMatchResultParams struct {
+	Delta    int32
+	ResumeID pgtype.UUID
+}
+
+func (q *Queries) ApplyResumeMatchResult(ctx context.Context, arg ApplyResumeMatchResultParams) error {
+	_, err := q.db.Exec(ctx, applyResumeMatchResult, arg.Delta, arg.ResumeID)
+	return err
+}
+
+const attributeGuestVotesToUser = `-- name: AttributeGuestVotesToUser :execrows
+update app.matches m
+set decided_by_user_id = $1
+where m.decided_by_guest_session_id = $2 and m.decided_by_user_id is null
+  and not exists (
+    select 1
+    from app.resumes r
+    where r.id in (m.resume_a_id, m.resume_b_id) and r.owner_user_id = $1
+  )
+`
+
+type AttributeGuestVotesToUserParams struct {
+	UserID         pgtype.UUID
+	GuestSessionID pgtype.UUID
+}
+
+// Attribution only: ratings already moved by the guest's (lower) weight stay as they are.
+func (q *Queries) AttributeGuestVotesToUser(ctx context.Context, arg AttributeGuestVotesToUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, attributeGuestVotesToUser, arg.UserID, arg.GuestSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const cancelMatch = `-- name: CancelMatch :one
+update app.matches
+set resolved_at = now(),
+    outcome = $2,
+    decided_by_user_id = $3,
+    decided_by_guest_session_id = $4,
+    state = 'cancelled'
+where id = $1 and state = 'created'
+returning id, resume_a_id, resume_b_id, industry, yoe_bucket, created_at, issued_to_user_id, issued_to_guest_session_id, resolved_at, winner_resume_id, loser_resume_id, decided_by_user_id, decided_by_guest_session_id, k_factor_used, delta_a, delta_b, state, outcome
+`
+
+type CancelMatchParams struct {
+	ID                      pgtype.UUID
+	Outcome                 pgtype.Text
+	DecidedByUserID         pgtype.UUID
+	DecidedByGuestSessionID pgtype.UUID
+}
+
+func (q *Queries) CancelMatch(ctx context.Context, arg CancelMatchParams) (AppMatch, error) {
+	row := q.db.QueryRow(ctx, cancelMatch,
+		arg.ID,
+		arg.Outcome,
+		arg.DecidedByUserID,
+		arg.DecidedByGuestSessionID,
+	)
+	var i AppMatch
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeAID,
+		&i.ResumeBID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CreatedAt,
+		&i.IssuedToUserID,
+		&i.IssuedToGuestSessionID,
+		&i.ResolvedAt,
+		&i.WinnerResumeID,
+		&i.LoserResumeID,
+		&i.DecidedByUserID,
+		&i.DecidedByGuestSessionID,
+		&i.KFactorUsed,
+		&i.DeltaA,
+		&i.DeltaB,
+		&i.State,
+		&i.Outcome,
+	)
+	return i, err
+}
+
+const cancelStaleMatches = `-- name: CancelStaleMatches :many
+update app.matches
+set resolved_at = now(),
+    state = 'cancelled'
+where id in (
+  select id
+  from app.matches
+  where state = 'created' and created_at < $1
+  order by created_at
+  limit $2
+  for update skip locked
+)
+returning resume_a_id, resume_b_id
+`
+
+type CancelStaleMatchesParams struct {
+	Cutoff    pgtype.Timestamptz
+	BatchSize int32
+}
+
+type CancelStaleMatchesRow struct {
+	ResumeAID pgtype.UUID
+	ResumeBID pgtype.UUID
+}
+
+// Cancel a batch of matches nobody voted on. SKIP LOCKED leaves matches that
+// are being resolved right now alone.
+func (q *Queries) CancelStaleMatches(ctx context.Context, arg CancelStaleMatchesParams) ([]CancelStaleMatchesRow, error) {
+	rows, err := q.db.Query(ctx, cancelStaleMatches, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CancelStaleMatchesRow
+	for rows.Next() {
+		var i CancelStaleMatchesRow
+		if err := rows.Scan(&i.ResumeAID, &i.ResumeBID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimResumeJobs = `-- name: ClaimResumeJobs :many
+update app.resume_jobs
+set state = 'running',
+    attempts = attempts + 1,
+    locked_at = now()
+where id in (
+  select j.id
+  from app.resume_jobs j
+  where j.state = 'queued' and j.run_at <= now()
+    and not exists (
+      select 1 from app.resume_jobs r
+      where r.resume_id = j.resume_id and r.kind = j.kind and r.state = 'running'
+    )
+  order by j.run_at, j.id
+  limit $1
+  for update skip locked
+)
+returning id, resume_id, kind, state, attempts, max_attempts, run_at, locked_at, last_error, created_at, finished_at
+`
+
+// A resume with a job of the same kind still running is skipped, so two renders of
+// one resume never overlap.
+func (q *Queries) ClaimResumeJobs(ctx context.Context, batchSize int32) ([]AppResumeJob, error) {
+	rows, err := q.db.Query(ctx, claimResumeJobs, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppResumeJob
+	for rows.Next() {
+		var i AppResumeJob
+		if err := rows.Scan(
+			&i.ID,
+			&i.ResumeID,
+			&i.Kind,
+			&i.State,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.RunAt,
+			&i.LockedAt,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimResumeTombstones = `-- name: ClaimResumeTombstones :many
+update app.resume_tombstones
+set run_at = $1
+where id in (
+  select id
+  from app.resume_tombstones
+  where run_at <= now()
+  order by run_at, id
+  limit $2
+  for update skip locked
+)
+returning id, resume_id, pdf_keys, pdf_prefix, image_prefix, legacy_image_prefix, attempts, last_error, run_at, created_at
+`
+
+type ClaimResumeTombstonesParams struct {
+	LeaseUntil pgtype.Timestamptz
+	BatchSize  int32
+}
+
+// Lease due tombstones by pushing run_at past the time a purge can take.
+func (q *Queries) ClaimResumeTombstones(ctx context.Context, arg ClaimResumeTombstonesParams) ([]AppResumeTombstone, error) {
+	rows, err := q.db.Query(ctx, claimResumeTombstones, arg.LeaseUntil, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppResumeTombstone
+	for rows.Next() {
+		var i AppResumeTombstone
+		if err := rows.Scan(
+			&i.ID,
+			&i.ResumeID,
+			&i.PdfKeys,
+			&i.PdfPrefix,
+			&i.ImagePrefix,
+			&i.LegacyImagePrefix,
+			&i.Attempts,
+			&i.LastError,
+			&i.RunAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimSupersededRenditions = `-- name: ClaimSupersededRenditions :many
+delete from app.superseded_renditions
+where superseded_at < $1
+returning image_key_prefix
+`
+
+// Renditions superseded before a refresh that started at @refreshed_from, which
+// no longer lists them.
+func (q *Queries) ClaimSupersededRenditions(ctx context.Context, refreshedFrom pgtype.Timestamptz) ([]string, error) {
+	rows, err := q.db.Query(ctx, claimSupersededRenditions, refreshedFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var image_key_prefix string
+		if err := rows.Scan(&image_key_prefix); err != nil {
+			return nil, err
+		}
+		items = append(items, image_key_prefix)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clearLegacyVersionRenditions = `-- name: ClearLegacyVersionRenditions :many
+update app.resume_versions v
+set image_key_prefix = null,
+    image_manifest = null,
+    rendered_hide_companies = null
+from app.resume_versions prev, app.resumes r
+where prev.id = v.id
+  and r.id = v.resume_id
+  and v.version <> r.current_version
+  and prev.image_key_prefix like 'users/%'
+returning prev.image_key_prefix::text as image_key_prefix
+`
+
+// Drop old-layout renditions from superseded versions, so rolling back to one
+// re-renders it. Returns the prefixes that were cleared.
+func (q *Queries) ClearLegacyVersionRenditions(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, clearLegacyVersionRenditions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var image_key_prefix string
+		if err := rows.Scan(&image_key_prefix); err != nil {
+			return nil, err
+		}
+		items = append(items, image_key_prefix)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeResumeJob = `-- name: CompleteResumeJob :exec
+update app.resume_jobs
+set state = 'succeeded',
+    locked_at = null,
+    last_error = null,
+    finished_at = now()
+where id = $1
+`
+
+func (q *Queries) CompleteResumeJob(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, completeResumeJob, id)
+	return err
+}
+
+const countFeedbackTagsForResume = `-- name: CountFeedbackTagsForResume :many
+select t.tag::text as tag, count(*) as count
+from app.feedback f
+cross join lateral unnest(f.tags) as t(tag)
+where f.target_resume_id = $1
+group by t.tag
+order by count(*) desc, t.tag
+`
+
+type CountFeedbackTagsForResumeRow struct {
+	Tag   string
+	Count int64
+}
+
+func (q *Queries) CountFeedbackTagsForResume(ctx context.Context, targetResumeID pgtype.UUID) ([]CountFeedbackTagsForResumeRow, error) {
+	rows, err := q.db.Query(ctx, countFeedbackTagsForResume, targetResumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountFeedbackTagsForResumeRow
+	for rows.Next() {
+		var i CountFeedbackTagsForResumeRow
+		if err := rows.Scan(&i.Tag, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countImageKeyPrefixReferences = `-- name: CountImageKeyPrefixReferences :one
+select (
+  (select count(*) from app.resumes where image_key_prefix = $1) +
+  (select count(*) from app.resume_versions where image_key_prefix = $1)
+)::bigint as count
+`
+
+func (q *Queries) CountImageKeyPrefixReferences(ctx context.Context, prefix pgtype.Text) (int64, error) {
+	row := q.db.QueryRow(ctx, countImageKeyPrefixReferences, prefix)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPdfStorageKeyReferences = `-- name: CountPdfStorageKeyReferences :one
+select (
+  (select count(*) from app.resumes where pdf_storage_key = $1) +
+  (select count(*) from app.resume_versions where pdf_storage_key = $1)
+)::bigint as count
+`
+
+func (q *Queries) CountPdfStorageKeyReferences(ctx context.Context, key pgtype.Text) (int64, error) {
+	row := q.db.QueryRow(ctx, countPdfStorageKeyReferences, key)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countResumeVersionsByImagePrefix = `-- name: CountResumeVersionsByImagePrefix :one
+select count(*)
+from app.resume_versions
+where image_key_prefix = $1
+`
+
+func (q *Queries) CountResumeVersionsByImagePrefix(ctx context.Context, imageKeyPrefix pgtype.Text) (int64, error) {
+	row := q.db.QueryRow(ctx, countResumeVersionsByImagePrefix, imageKeyPrefix)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createFeedback = `-- name: CreateFeedback :one
+
+
+
+insert into app.feedback (
+  match_id, target_resume_id, author_user_id, visibility, text, tags
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning id, match_id, target_resume_id, author_user_id, visibility, text, tags, created_at
+`
+
+type CreateFeedbackParams struct {
+	MatchID        pgtype.UUID
+	TargetResumeID pgtype.UUID
+	AuthorUserID   pgtype.UUID
+	Visibility     string
+	Text           string
+	Tags           []string
+}
+
+// --------------------- END OF LEADERBOARD RELATED QUERIES ----------------------------------------
+// --------------------- START OF FEEDBACK RELATED QUERIES ----------------------------------------
+func (q *Queries) CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (AppFeedback, error) {
+	row := q.db.QueryRow(ctx, createFeedback,
+		arg.MatchID,
+		arg.TargetResumeID,
+		arg.AuthorUserID,
+		arg.Visibility,
+		arg.Text,
+		arg.Tags,
+	)
+	var i AppFeedback
+	err := row.Scan(
+		&i.ID,
+		&i.MatchID,
+		&i.TargetResumeID,
+		&i.AuthorUserID,
+		&i.Visibility,
+		&i.Text,
+		&i.Tags,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createGuestSession = `-- name: CreateGuestSession :one
+
+
+insert into app.guest_sessions (expires_at)
+values ($1)
+returning id, created_at, expires_at, merged_into_user_id, merged_at
+`
+
+// --------------------- END OF VOTE VIOLATION RELATED QUERIES ----------------------------------------
+// --------------------- START OF GUEST SESSION RELATED QUERIES ----------------------------------------
+func (q *Queries) CreateGuestSession(ctx context.Context, expiresAt pgtype.Timestamptz) (AppGuestSession, error) {
+	row := q.db.QueryRow(ctx, createGuestSession, expiresAt)
+	var i AppGuestSession
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.MergedIntoUserID,
+		&i.MergedAt,
+	)
+	return i, err
+}
+
+const createMatch = `-- name: CreateMatch :one
+insert into app.matches (
+  resume_a_id, resume_b_id, industry, yoe_bucket, issued_to_user_id, issued_to_guest_session_id
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning id, resume_a_id, resume_b_id, industry, yoe_bucket, created_at, issued_to_user_id, issued_to_guest_session_id, resolved_at, winner_resume_id, loser_resume_id, decided_by_user_id, decided_by_guest_session_id, k_factor_used, delta_a, delta_b, state, outcome
+`
+
+type CreateMatchParams struct {
+	ResumeAID              pgtype.UUID
+	ResumeBID              pgtype.UUID
+	Industry               string
+	YoeBucket              string
+	IssuedToUserID         pgtype.UUID
+	IssuedToGuestSessionID pgtype.UUID
+}
+
+func (q *Queries) CreateMatch(ctx context.Context, arg CreateMatchParams) (AppMatch, error) {
+	row := q.db.QueryRow(ctx, createMatch,
+		arg.ResumeAID,
+		arg.ResumeBID,
+		arg.Industry,
+		arg.YoeBucket,
+		arg.IssuedToUserID,
+		arg.IssuedToGuestSessionID,
+	)
+	var i AppMatch
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeAID,
+		&i.ResumeBID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CreatedAt,
+		&i.IssuedToUserID,
+		&i.IssuedToGuestSessionID,
+		&i.ResolvedAt,
+		&i.WinnerResumeID,
+		&i.LoserResumeID,
+		&i.DecidedByUserID,
+		&i.DecidedByGuestSessionID,
+		&i.KFactorUsed,
+		&i.DeltaA,
+		&i.DeltaB,
+		&i.State,
+		&i.Outcome,
+	)
+	return i, err
+}
+
+const createResumeTombstone = `-- name: CreateResumeTombstone :one
+insert into app.resume_tombstones (
+  resume_id, pdf_keys, pdf_prefix, image_prefix, legacy_image_prefix, run_at
+) values (
+  $1, $2, $3, $4, $5, $6
+)
+returning id, resume_id, pdf_keys, pdf_prefix, image_prefix, legacy_image_prefix, attempts, last_error, run_at, created_at
+`
+
+type CreateResumeTombstoneParams struct {
+	ResumeID          pgtype.UUID
+	PdfKeys           []string
+	PdfPrefix         string
+	ImagePrefix       string
+	LegacyImagePrefix string
+	RunAt             pgtype.Timestamptz
+}
+
+func (q *Queries) CreateResumeTombstone(ctx context.Context, arg CreateResumeTombstoneParams) (AppResumeTombstone, error) {
+	row := q.db.QueryRow(ctx, createResumeTombstone,
+		arg.ResumeID,
+		arg.PdfKeys,
+		arg.PdfPrefix,
+		arg.ImagePrefix,
+		arg.LegacyImagePrefix,
+		arg.RunAt,
+	)
+	var i AppResumeTombstone
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeID,
+		&i.PdfKeys,
+		&i.PdfPrefix,
+		&i.ImagePrefix,
+		&i.LegacyImagePrefix,
+		&i.Attempts,
+		&i.LastError,
+		&i.RunAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createResumeVersion = `-- name: CreateResumeVersion :one
+insert into app.resume_versions (
+  resume_id, version, pdf_storage_key, pdf_size_bytes, page_count
+) values (
+  $1, $2, $3, $4, $5
+)
+returning id, resume_id, version, pdf_storage_key, pdf_size_bytes, page_count, image_key_prefix, image_manifest, final_elo_int, final_battles_count, created_at, rendered_hide_companies
+`
+
+type CreateResumeVersionParams struct {
+	ResumeID      pgtype.UUID
+	Version       int32
+	PdfStorageKey pgtype.Text
+	PdfSizeBytes  pgtype.Int8
+	PageCount     int16
+}
+
+func (q *Queries) CreateResumeVersion(ctx context.Context, arg CreateResumeVersionParams) (AppResumeVersion, error) {
+	row := q.db.QueryRow(ctx, createResumeVersion,
+		arg.ResumeID,
+		arg.Version,
+		arg.PdfStorageKey,
+		arg.PdfSizeBytes,
+		arg.PageCount,
+	)
+	var i AppResumeVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeID,
+		&i.Version,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PageCount,
+		&i.ImageKeyPrefix,
+		&i.ImageManifest,
+		&i.FinalEloInt,
+		&i.FinalBattlesCount,
+		&i.CreatedAt,
+		&i.RenderedHideCompanies,
+	)
+	return i, err
+}
+
 const createResumeWithSlot = `-- name: CreateResumeWithSlot :one
 insert into app.resumes (
   owner_user_id, slot, name, industry, yoe_bucket,
   pdf_storage_key, pdf_size_bytes, pdf_mime,
-  image_key_prefix, page_count, image_ready
+  image_key_prefix, page_count, image_ready, hide_companies, id
 ) values (
   $1, $2, $3, $4, $5,
   $6, $7, coalesce($8, 'application/pdf'),
-  $9, coalesce($10, 1), coalesce($11, false)
+  $9, coalesce($10, 1), coalesce($11, false), $12, $13
 )
-returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
 `
 
 type CreateResumeWithSlotParams struct {
@@ -36,6 +640,8 @@ type CreateResumeWithSlotParams struct {
 	ImageKeyPrefix pgtype.Text
 	Column10       interface{}
 	Column11       interface{}
+	HideCompanies  bool
+	ID             pgtype.UUID
 }
 
 // Create ----------------------------------------------------------------
@@ -52,6 +658,8 @@ func (q *Queries) CreateResumeWithSlot(ctx context.Context, arg CreateResumeWith
 		arg.ImageKeyPrefix,
 		arg.Column10,
 		arg.Column11,
+		arg.HideCompanies,
+		arg.ID,
 	)
 	var i AppResume
 	err := row.Scan(
@@ -60,68 +668,1675 @@ func (q *Queries) CreateResumeWithSlot(ctx context.Context, arg CreateResumeWith
 		&i.OwnerUserID,
 		&i.Industry,
 		&i.YoeBucket,
-		&i.CurrentEloInt,
-		&i.BattlesCount,
-		&i.LastMatchedAt,
-		&i.InFlight,
+		&i.CurrentEloInt,
+		&i.BattlesCount,
+		&i.LastMatchedAt,
+		&i.InFlight,
+		&i.CreatedAt,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PdfMime,
+		&i.ImageKeyPrefix,
+		&i.PageCount,
+		&i.ImageReady,
+		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
+	)
+	return i, err
+}
+
+const createUploadIntent = `-- name: CreateUploadIntent :one
+insert into app.upload_intents (
+  id, owner_user_id, slot, name, industry, yoe_bucket, hide_companies, object_key, expires_at
+) values (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+returning id, owner_user_id, slot, name, industry, yoe_bucket, hide_companies, object_key, expires_at, created_at
+`
+
+type CreateUploadIntentParams struct {
+	ID            pgtype.UUID
+	OwnerUserID   pgtype.UUID
+	Slot          int16
+	Name          string
+	Industry      string
+	YoeBucket     string
+	HideCompanies bool
+	ObjectKey     string
+	ExpiresAt     pgtype.Timestamptz
+}
+
+func (q *Queries) CreateUploadIntent(ctx context.Context, arg CreateUploadIntentParams) (AppUploadIntent, error) {
+	row := q.db.QueryRow(ctx, createUploadIntent,
+		arg.ID,
+		arg.OwnerUserID,
+		arg.Slot,
+		arg.Name,
+		arg.Industry,
+		arg.YoeBucket,
+		arg.HideCompanies,
+		arg.ObjectKey,
+		arg.ExpiresAt,
+	)
+	var i AppUploadIntent
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerUserID,
+		&i.Slot,
+		&i.Name,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.HideCompanies,
+		&i.ObjectKey,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createVoteViolation = `-- name: CreateVoteViolation :exec
+
+
+insert into app.vote_violations (match_id, user_id, guest_session_id, reason)
+values ($1, $2, $3, $4)
+`
+
+type CreateVoteViolationParams struct {
+	MatchID        pgtype.UUID
+	UserID         pgtype.UUID
+	GuestSessionID pgtype.UUID
+	Reason         string
+}
+
+// --------------------- END OF RESUME TOMBSTONE RELATED QUERIES ----------------------------------------
+// --------------------- START OF VOTE VIOLATION RELATED QUERIES ----------------------------------------
+func (q *Queries) CreateVoteViolation(ctx context.Context, arg CreateVoteViolationParams) error {
+	_, err := q.db.Exec(ctx, createVoteViolation,
+		arg.MatchID,
+		arg.UserID,
+		arg.GuestSessionID,
+		arg.Reason,
+	)
+	return err
+}
+
+const deferRenditionDeletion = `-- name: DeferRenditionDeletion :exec
+insert into app.superseded_renditions (image_key_prefix)
+values ($1)
+on conflict (image_key_prefix) do nothing
+`
+
+func (q *Queries) DeferRenditionDeletion(ctx context.Context, imageKeyPrefix string) error {
+	_, err := q.db.Exec(ctx, deferRenditionDeletion, imageKeyPrefix)
+	return err
+}
+
+const deleteExpiredUploadIntentsForOwner = `-- name: DeleteExpiredUploadIntentsForOwner :many
+
+
+delete from app.upload_intents
+where owner_user_id = $1 and expires_at <= now()
+returning object_key
+`
+
+// --------------------- END OF GUEST SESSION RELATED QUERIES ----------------------------------------
+// --------------------- START OF UPLOAD INTENT RELATED QUERIES ----------------------------------------
+// Frees slots held by abandoned uploads so the unique (owner, slot) index doesn't trip.
+// Returns the staged keys so the caller can delete whatever was uploaded to them.
+func (q *Queries) DeleteExpiredUploadIntentsForOwner(ctx context.Context, ownerUserID pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, deleteExpiredUploadIntentsForOwner, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var object_key string
+		if err := rows.Scan(&object_key); err != nil {
+			return nil, err
+		}
+		items = append(items, object_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteResumeByIDForOwner = `-- name: DeleteResumeByIDForOwner :exec
+delete from app.resumes
+where id = $1 and owner_user_id = $2
+`
+
+type DeleteResumeByIDForOwnerParams struct {
+	ID          pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+// Delete ---------------------------------------------------------------
+func (q *Queries) DeleteResumeByIDForOwner(ctx context.Context, arg DeleteResumeByIDForOwnerParams) error {
+	_, err := q.db.Exec(ctx, deleteResumeByIDForOwner, arg.ID, arg.OwnerUserID)
+	return err
+}
+
+const deleteResumeTombstone = `-- name: DeleteResumeTombstone :exec
+delete from app.resume_tombstones
+where id = $1
+`
+
+func (q *Queries) DeleteResumeTombstone(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteResumeTombstone, id)
+	return err
+}
+
+const deleteUploadIntent = `-- name: DeleteUploadIntent :exec
+delete from app.upload_intents
+where id = $1
+`
+
+func (q *Queries) DeleteUploadIntent(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUploadIntent, id)
+	return err
+}
+
+const enqueueResumeJob = `-- name: EnqueueResumeJob :one
+
+
+
+with job as (
+  insert into app.resume_jobs (resume_id, kind, max_attempts)
+  values ($1, $2, $3)
+  on conflict (resume_id, kind) where state = 'queued' do nothing
+  returning id, resume_id, kind, state, attempts, max_attempts, run_at, locked_at, last_error, created_at, finished_at
+)
+, queued as (
+  update app.resumes
+  set processing_state = 'queued',
+      processing_error = null
+  where id = (select resume_id from job)
+  returning id
+)
+select job.id, job.resume_id, job.kind, job.state, job.attempts, job.max_attempts, job.run_at, job.locked_at, job.last_error, job.created_at, job.finished_at
+from job
+left join (select pg_notify('resume_processing', id::text) from queued) notified on true
+`
+
+type EnqueueResumeJobParams struct {
+	ResumeID    pgtype.UUID
+	Kind        string
+	MaxAttempts int32
+}
+
+// --------------------- END OF FEEDBACK RELATED QUERIES ----------------------------------------
+// --------------------- START OF RESUME JOB RELATED QUERIES ----------------------------------------
+// Returns no rows when the same job is already waiting to run. A new job marks the
+// resume queued in the same statement (announced like SetResumeProcessingState), so
+// a worker that claims it can't have its progress overwritten afterwards.
+func (q *Queries) EnqueueResumeJob(ctx context.Context, arg EnqueueResumeJobParams) (AppResumeJob, error) {
+	row := q.db.QueryRow(ctx, enqueueResumeJob, arg.ResumeID, arg.Kind, arg.MaxAttempts)
+	var i AppResumeJob
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeID,
+		&i.Kind,
+		&i.State,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LockedAt,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const failResumeJob = `-- name: FailResumeJob :exec
+update app.resume_jobs
+set state = 'failed',
+    locked_at = null,
+    last_error = $1,
+    finished_at = now()
+where id = $2
+`
+
+type FailResumeJobParams struct {
+	LastError pgtype.Text
+	ID        pgtype.UUID
+}
+
+func (q *Queries) FailResumeJob(ctx context.Context, arg FailResumeJobParams) error {
+	_, err := q.db.Exec(ctx, failResumeJob, arg.LastError, arg.ID)
+	return err
+}
+
+const findFreeSlotForOwner = `-- name: FindFreeSlotForOwner :one
+
+with slots as (select unnest(array[1,2,3])::smallint as slot)
+select s.slot
+from slots s
+left join app.resumes r
+  on r.owner_user_id = $1 and r.slot = s.slot
+left join app.upload_intents u
+  on u.owner_user_id = $1 and u.slot = s.slot and u.expires_at > now()
+where r.id is null and u.id is null
+order by s.slot
+limit 1
+`
+
+// --------------------- START OF RESUME RELATED QUERIES ----------------------------------------
+// Allocate a free slot (1..3) for an owner -------------------------------
+// Slots reserved by a pending direct upload count as taken.
+func (q *Queries) FindFreeSlotForOwner(ctx context.Context, ownerUserID pgtype.UUID) (int16, error) {
+	row := q.db.QueryRow(ctx, findFreeSlotForOwner, ownerUserID)
+	var slot int16
+	err := row.Scan(&slot)
+	return slot, err
+}
+
+const getGuestSession = `-- name: GetGuestSession :one
+select id, created_at, expires_at, merged_into_user_id, merged_at
+from app.guest_sessions
+where id = $1
+`
+
+func (q *Queries) GetGuestSession(ctx context.Context, id pgtype.UUID) (AppGuestSession, error) {
+	row := q.db.QueryRow(ctx, getGuestSession, id)
+	var i AppGuestSession
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.MergedIntoUserID,
+		&i.MergedAt,
+	)
+	return i, err
+}
+
+const getMatchByID = `-- name: GetMatchByID :one
+select id, resume_a_id, resume_b_id, industry, yoe_bucket, created_at, issued_to_user_id, issued_to_guest_session_id, resolved_at, winner_resume_id, loser_resume_id, decided_by_user_id, decided_by_guest_session_id, k_factor_used, delta_a, delta_b, state, outcome
+from app.matches
+where id = $1
+`
+
+func (q *Queries) GetMatchByID(ctx context.Context, id pgtype.UUID) (AppMatch, error) {
+	row := q.db.QueryRow(ctx, getMatchByID, id)
+	var i AppMatch
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeAID,
+		&i.ResumeBID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CreatedAt,
+		&i.IssuedToUserID,
+		&i.IssuedToGuestSessionID,
+		&i.ResolvedAt,
+		&i.WinnerResumeID,
+		&i.LoserResumeID,
+		&i.DecidedByUserID,
+		&i.DecidedByGuestSessionID,
+		&i.KFactorUsed,
+		&i.DeltaA,
+		&i.DeltaB,
+		&i.State,
+		&i.Outcome,
+	)
+	return i, err
+}
+
+const getMatchByIDForUpdate = `-- name: GetMatchByIDForUpdate :one
+select id, resume_a_id, resume_b_id, industry, yoe_bucket, created_at, issued_to_user_id, issued_to_guest_session_id, resolved_at, winner_resume_id, loser_resume_id, decided_by_user_id, decided_by_guest_session_id, k_factor_used, delta_a, delta_b, state, outcome
+from app.matches
+where id = $1
+for update
+`
+
+func (q *Queries) GetMatchByIDForUpdate(ctx context.Context, id pgtype.UUID) (AppMatch, error) {
+	row := q.db.QueryRow(ctx, getMatchByIDForUpdate, id)
+	var i AppMatch
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeAID,
+		&i.ResumeBID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CreatedAt,
+		&i.IssuedToUserID,
+		&i.IssuedToGuestSessionID,
+		&i.ResolvedAt,
+		&i.WinnerResumeID,
+		&i.LoserResumeID,
+		&i.DecidedByUserID,
+		&i.DecidedByGuestSessionID,
+		&i.KFactorUsed,
+		&i.DeltaA,
+		&i.DeltaB,
+		&i.State,
+		&i.Outcome,
+	)
+	return i, err
+}
+
+const getResumeByID = `-- name: GetResumeByID :one
+select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
+from app.resumes
+where id = $1
+`
+
+// Read ------------------------------------------------------------------
+func (q *Queries) GetResumeByID(ctx context.Context, id pgtype.UUID) (AppResume, error) {
+	row := q.db.QueryRow(ctx, getResumeByID, id)
+	var i AppResume
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerUserID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CurrentEloInt,
+		&i.BattlesCount,
+		&i.LastMatchedAt,
+		&i.InFlight,
+		&i.CreatedAt,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PdfMime,
+		&i.ImageKeyPrefix,
+		&i.PageCount,
+		&i.ImageReady,
+		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
+	)
+	return i, err
+}
+
+const getResumeByIDForOwner = `-- name: GetResumeByIDForOwner :one
+select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
+from app.resumes
+where id = $1 and owner_user_id = $2
+`
+
+type GetResumeByIDForOwnerParams struct {
+	ID          pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+func (q *Queries) GetResumeByIDForOwner(ctx context.Context, arg GetResumeByIDForOwnerParams) (AppResume, error) {
+	row := q.db.QueryRow(ctx, getResumeByIDForOwner, arg.ID, arg.OwnerUserID)
+	var i AppResume
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerUserID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CurrentEloInt,
+		&i.BattlesCount,
+		&i.LastMatchedAt,
+		&i.InFlight,
+		&i.CreatedAt,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PdfMime,
+		&i.ImageKeyPrefix,
+		&i.PageCount,
+		&i.ImageReady,
+		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
+	)
+	return i, err
+}
+
+const getResumeByIDForOwnerForUpdate = `-- name: GetResumeByIDForOwnerForUpdate :one
+select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
+from app.resumes
+where id = $1 and owner_user_id = $2
+for update
+`
+
+type GetResumeByIDForOwnerForUpdateParams struct {
+	ID          pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+func (q *Queries) GetResumeByIDForOwnerForUpdate(ctx context.Context, arg GetResumeByIDForOwnerForUpdateParams) (AppResume, error) {
+	row := q.db.QueryRow(ctx, getResumeByIDForOwnerForUpdate, arg.ID, arg.OwnerUserID)
+	var i AppResume
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerUserID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CurrentEloInt,
+		&i.BattlesCount,
+		&i.LastMatchedAt,
+		&i.InFlight,
+		&i.CreatedAt,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PdfMime,
+		&i.ImageKeyPrefix,
+		&i.PageCount,
+		&i.ImageReady,
+		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
+	)
+	return i, err
+}
+
+const getResumeProcessingStatusForOwner = `-- name: GetResumeProcessingStatusForOwner :one
+select id, processing_state, processing_error, image_ready, image_key_prefix, image_manifest
+from app.resumes
+where id = $1 and owner_user_id = $2
+`
+
+type GetResumeProcessingStatusForOwnerParams struct {
+	ID          pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+type GetResumeProcessingStatusForOwnerRow struct {
+	ID              pgtype.UUID
+	ProcessingState string
+	ProcessingError pgtype.Text
+	ImageReady      bool
+	ImageKeyPrefix  pgtype.Text
+	ImageManifest   json.RawMessage
+}
+
+func (q *Queries) GetResumeProcessingStatusForOwner(ctx context.Context, arg GetResumeProcessingStatusForOwnerParams) (GetResumeProcessingStatusForOwnerRow, error) {
+	row := q.db.QueryRow(ctx, getResumeProcessingStatusForOwner, arg.ID, arg.OwnerUserID)
+	var i GetResumeProcessingStatusForOwnerRow
+	err := row.Scan(
+		&i.ID,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageReady,
+		&i.ImageKeyPrefix,
+		&i.ImageManifest,
+	)
+	return i, err
+}
+
+const getResumeVersion = `-- name: GetResumeVersion :one
+select id, resume_id, version, pdf_storage_key, pdf_size_bytes, page_count, image_key_prefix, image_manifest, final_elo_int, final_battles_count, created_at, rendered_hide_companies
+from app.resume_versions
+where resume_id = $1 and version = $2
+`
+
+type GetResumeVersionParams struct {
+	ResumeID pgtype.UUID
+	Version  int32
+}
+
+func (q *Queries) GetResumeVersion(ctx context.Context, arg GetResumeVersionParams) (AppResumeVersion, error) {
+	row := q.db.QueryRow(ctx, getResumeVersion, arg.ResumeID, arg.Version)
+	var i AppResumeVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeID,
+		&i.Version,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PageCount,
+		&i.ImageKeyPrefix,
+		&i.ImageManifest,
+		&i.FinalEloInt,
+		&i.FinalBattlesCount,
+		&i.CreatedAt,
+		&i.RenderedHideCompanies,
+	)
+	return i, err
+}
+
+const getUploadIntentForOwner = `-- name: GetUploadIntentForOwner :one
+select id, owner_user_id, slot, name, industry, yoe_bucket, hide_companies, object_key, expires_at, created_at
+from app.upload_intents
+where id = $1 and owner_user_id = $2
+`
+
+type GetUploadIntentForOwnerParams struct {
+	ID          pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+func (q *Queries) GetUploadIntentForOwner(ctx context.Context, arg GetUploadIntentForOwnerParams) (AppUploadIntent, error) {
+	row := q.db.QueryRow(ctx, getUploadIntentForOwner, arg.ID, arg.OwnerUserID)
+	var i AppUploadIntent
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerUserID,
+		&i.Slot,
+		&i.Name,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.HideCompanies,
+		&i.ObjectKey,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const isLeaderboardPopulated = `-- name: IsLeaderboardPopulated :one
+select ispopulated
+from pg_matviews
+where schemaname = 'app' and matviewname = 'leaderboard'
+`
+
+func (q *Queries) IsLeaderboardPopulated(ctx context.Context) (pgtype.Bool, error) {
+	row := q.db.QueryRow(ctx, isLeaderboardPopulated)
+	var ispopulated pgtype.Bool
+	err := row.Scan(&ispopulated)
+	return ispopulated, err
+}
+
+const listFeedbackForResume = `-- name: ListFeedbackForResume :many
+select id, match_id, target_resume_id, author_user_id, visibility, text, tags, created_at
+from app.feedback
+where target_resume_id = $1
+order by created_at desc, id
+`
+
+func (q *Queries) ListFeedbackForResume(ctx context.Context, targetResumeID pgtype.UUID) ([]AppFeedback, error) {
+	rows, err := q.db.Query(ctx, listFeedbackForResume, targetResumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppFeedback
+	for rows.Next() {
+		var i AppFeedback
+		if err := rows.Scan(
+			&i.ID,
+			&i.MatchID,
+			&i.TargetResumeID,
+			&i.AuthorUserID,
+			&i.Visibility,
+			&i.Text,
+			&i.Tags,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImageKeyPrefixes = `-- name: ListImageKeyPrefixes :many
+select image_key_prefix::text as image_key_prefix
+from app.resumes
+where image_key_prefix is not null and image_key_prefix <> ''
+union
+select image_key_prefix::text
+from app.resume_versions
+where image_key_prefix is not null and image_key_prefix <> ''
+union
+select image_key_prefix
+from app.superseded_renditions
+`
+
+// Every rendition prefix referenced by a resume or one of its versions, or possibly
+// still listed by the materialized leaderboard.
+func (q *Queries) ListImageKeyPrefixes(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, listImageKeyPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var image_key_prefix string
+		if err := rows.Scan(&image_key_prefix); err != nil {
+			return nil, err
+		}
+		items = append(items, image_key_prefix)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeaderboard = `-- name: ListLeaderboard :many
+
+
+
+select id, current_elo_int, battles_count, image_key_prefix, image_manifest
+from app.resumes
+where industry = $1 and yoe_bucket = $2 and image_ready
+  and (
+    $3::integer is null
+    or current_elo_int < $3::integer
+    or (current_elo_int = $3::integer and id > $4::uuid)
+  )
+order by current_elo_int desc, id
+limit $5
+`
+
+type ListLeaderboardParams struct {
+	Industry  string
+	YoeBucket string
+	CursorElo pgtype.Int4
+	CursorID  pgtype.UUID
+	RowLimit  int32
+}
+
+type ListLeaderboardRow struct {
+	ID             pgtype.UUID
+	CurrentEloInt  int32
+	BattlesCount   int32
+	ImageKeyPrefix pgtype.Text
+	ImageManifest  json.RawMessage
+}
+
+// --------------------- END OF MATCHMAKING RELATED QUERIES ----------------------------------------
+// --------------------- START OF LEADERBOARD RELATED QUERIES ----------------------------------------
+// Keyset pagination on (current_elo_int desc, id). Pass a null cursor for the first page.
+func (q *Queries) ListLeaderboard(ctx context.Context, arg ListLeaderboardParams) ([]ListLeaderboardRow, error) {
+	rows, err := q.db.Query(ctx, listLeaderboard,
+		arg.Industry,
+		arg.YoeBucket,
+		arg.CursorElo,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLeaderboardRow
+	for rows.Next() {
+		var i ListLeaderboardRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CurrentEloInt,
+			&i.BattlesCount,
+			&i.ImageKeyPrefix,
+			&i.ImageManifest,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLeaderboardMaterialized = `-- name: ListLeaderboardMaterialized :many
+select resume_id, current_elo_int, battles_count, image_key_prefix, image_manifest
+from app.leaderboard
+where industry = $1 and yoe_bucket = $2
+  and (
+    $3::integer is null
+    or current_elo_int < $3::integer
+    or (current_elo_int = $3::integer and resume_id > $4::uuid)
+  )
+order by current_elo_int desc, resume_id
+limit $5
+`
+
+type ListLeaderboardMaterializedParams struct {
+	Industry  string
+	YoeBucket string
+	CursorElo pgtype.Int4
+	CursorID  pgtype.UUID
+	RowLimit  int32
+}
+
+type ListLeaderboardMaterializedRow struct {
+	ResumeID       pgtype.UUID
+	CurrentEloInt  int32
+	BattlesCount   int32
+	ImageKeyPrefix pgtype.Text
+	ImageManifest  json.RawMessage
+}
+
+// Same shape and ordering as ListLeaderboard, read from the materialized view.
+func (q *Queries) ListLeaderboardMaterialized(ctx context.Context, arg ListLeaderboardMaterializedParams) ([]ListLeaderboardMaterializedRow, error) {
+	rows, err := q.db.Query(ctx, listLeaderboardMaterialized,
+		arg.Industry,
+		arg.YoeBucket,
+		arg.CursorElo,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLeaderboardMaterializedRow
+	for rows.Next() {
+		var i ListLeaderboardMaterializedRow
+		if err := rows.Scan(
+			&i.ResumeID,
+			&i.CurrentEloInt,
+			&i.BattlesCount,
+			&i.ImageKeyPrefix,
+			&i.ImageManifest,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLegacyRenditionResumeIDs = `-- name: ListLegacyRenditionResumeIDs :many
+select id
+from app.resumes
+where image_key_prefix like 'users/%'
+order by id
+`
+
+// Resumes whose current preview is still under the old users/{userId}/ webp layout.
+func (q *Queries) ListLegacyRenditionResumeIDs(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listLegacyRenditionResumeIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMatchesByResume = `-- name: ListMatchesByResume :many
+select id, resume_a_id, resume_b_id, industry, yoe_bucket, created_at, issued_to_user_id, issued_to_guest_session_id, resolved_at, winner_resume_id, loser_resume_id, decided_by_user_id, decided_by_guest_session_id, k_factor_used, delta_a, delta_b, state, outcome
+from app.matches
+where resume_a_id = $1 or resume_b_id = $1
+order by created_at desc, id
+limit $2 offset $3
+`
+
+type ListMatchesByResumeParams struct {
+	ResumeID  pgtype.UUID
+	RowLimit  int32
+	RowOffset int32
+}
+
+func (q *Queries) ListMatchesByResume(ctx context.Context, arg ListMatchesByResumeParams) ([]AppMatch, error) {
+	rows, err := q.db.Query(ctx, listMatchesByResume, arg.ResumeID, arg.RowLimit, arg.RowOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppMatch
+	for rows.Next() {
+		var i AppMatch
+		if err := rows.Scan(
+			&i.ID,
+			&i.ResumeAID,
+			&i.ResumeBID,
+			&i.Industry,
+			&i.YoeBucket,
+			&i.CreatedAt,
+			&i.IssuedToUserID,
+			&i.IssuedToGuestSessionID,
+			&i.ResolvedAt,
+			&i.WinnerResumeID,
+			&i.LoserResumeID,
+			&i.DecidedByUserID,
+			&i.DecidedByGuestSessionID,
+			&i.KFactorUsed,
+			&i.DeltaA,
+			&i.DeltaB,
+			&i.State,
+			&i.Outcome,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnerSlots = `-- name: ListOwnerSlots :many
+select slot
+from app.resumes
+where owner_user_id = $1
+order by slot
+`
+
+// Convenience ----------------------------------------------------------
+func (q *Queries) ListOwnerSlots(ctx context.Context, ownerUserID pgtype.UUID) ([]int16, error) {
+	rows, err := q.db.Query(ctx, listOwnerSlots, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int16
+	for rows.Next() {
+		var slot int16
+		if err := rows.Scan(&slot); err != nil {
+			return nil, err
+		}
+		items = append(items, slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPdfStorageKeys = `-- name: ListPdfStorageKeys :many
+
+
+
+select r.id as resume_id, r.owner_user_id, r.pdf_storage_key
+from app.resumes r
+where r.pdf_storage_key is not null
+union
+select v.resume_id, r.owner_user_id, v.pdf_storage_key
+from app.resume_versions v
+join app.resumes r on r.id = v.resume_id
+where v.pdf_storage_key is not null
+order by resume_id, pdf_storage_key
+`
+
+type ListPdfStorageKeysRow struct {
+	ResumeID      pgtype.UUID
+	OwnerUserID   pgtype.UUID
+	PdfStorageKey pgtype.Text
+}
+
+// --------------------- END OF RESUME VERSION RELATED QUERIES ----------------------------------------
+// --------------------- START OF STORAGE KEY RELATED QUERIES ----------------------------------------
+// Every PDF key referenced by a resume or one of its versions.
+func (q *Queries) ListPdfStorageKeys(ctx context.Context) ([]ListPdfStorageKeysRow, error) {
+	rows, err := q.db.Query(ctx, listPdfStorageKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPdfStorageKeysRow
+	for rows.Next() {
+		var i ListPdfStorageKeysRow
+		if err := rows.Scan(&i.ResumeID, &i.OwnerUserID, &i.PdfStorageKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listResumePdfKeys = `-- name: ListResumePdfKeys :many
+
+
+
+select pdf_storage_key::text as pdf_storage_key
+from app.resumes
+where id = $1 and pdf_storage_key is not null
+union
+select pdf_storage_key::text
+from app.resume_versions
+where resume_id = $1 and pdf_storage_key is not null
+`
+
+// --------------------- END OF STORAGE KEY RELATED QUERIES ----------------------------------------
+// --------------------- START OF RESUME TOMBSTONE RELATED QUERIES ----------------------------------------
+// Every PDF key a resume or any of its versions points at.
+func (q *Queries) ListResumePdfKeys(ctx context.Context, resumeID pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, listResumePdfKeys, resumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var pdf_storage_key string
+		if err := rows.Scan(&pdf_storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, pdf_storage_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listResumeVersions = `-- name: ListResumeVersions :many
+select id, resume_id, version, pdf_storage_key, pdf_size_bytes, page_count, image_key_prefix, image_manifest, final_elo_int, final_battles_count, created_at, rendered_hide_companies
+from app.resume_versions
+where resume_id = $1
+order by version desc
+`
+
+func (q *Queries) ListResumeVersions(ctx context.Context, resumeID pgtype.UUID) ([]AppResumeVersion, error) {
+	rows, err := q.db.Query(ctx, listResumeVersions, resumeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppResumeVersion
+	for rows.Next() {
+		var i AppResumeVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.ResumeID,
+			&i.Version,
+			&i.PdfStorageKey,
+			&i.PdfSizeBytes,
+			&i.PageCount,
+			&i.ImageKeyPrefix,
+			&i.ImageManifest,
+			&i.FinalEloInt,
+			&i.FinalBattlesCount,
+			&i.CreatedAt,
+			&i.RenderedHideCompanies,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listResumesByOwner = `-- name: ListResumesByOwner :many
+select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
+from app.resumes
+where owner_user_id = $1
+order by created_at desc, id
+limit $2 offset $3
+`
+
+type ListResumesByOwnerParams struct {
+	OwnerUserID pgtype.UUID
+	Limit       int32
+	Offset      int32
+}
+
+func (q *Queries) ListResumesByOwner(ctx context.Context, arg ListResumesByOwnerParams) ([]AppResume, error) {
+	rows, err := q.db.Query(ctx, listResumesByOwner, arg.OwnerUserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AppResume
+	for rows.Next() {
+		var i AppResume
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.OwnerUserID,
+			&i.Industry,
+			&i.YoeBucket,
+			&i.CurrentEloInt,
+			&i.BattlesCount,
+			&i.LastMatchedAt,
+			&i.InFlight,
+			&i.CreatedAt,
+			&i.PdfStorageKey,
+			&i.PdfSizeBytes,
+			&i.PdfMime,
+			&i.ImageKeyPrefix,
+			&i.PageCount,
+			&i.ImageReady,
+			&i.Slot,
+			&i.HideCompanies,
+			&i.ProcessingState,
+			&i.ProcessingError,
+			&i.ImageManifest,
+			&i.CurrentVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUploadIntentKeys = `-- name: ListUploadIntentKeys :many
+select object_key
+from app.upload_intents
+where expires_at > now()
+`
+
+// Staged keys that can still be finalized; the orphan sweep leaves these alone.
+func (q *Queries) ListUploadIntentKeys(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, listUploadIntentKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var object_key string
+		if err := rows.Scan(&object_key); err != nil {
+			return nil, err
+		}
+		items = append(items, object_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockResumePairForUpdate = `-- name: LockResumePairForUpdate :many
+select id, current_elo_int, battles_count
+from app.resumes
+where id = $1 or id = $2
+order by id
+for update
+`
+
+type LockResumePairForUpdateParams struct {
+	ResumeAID pgtype.UUID
+	ResumeBID pgtype.UUID
+}
+
+type LockResumePairForUpdateRow struct {
+	ID            pgtype.UUID
+	CurrentEloInt int32
+	BattlesCount  int32
+}
+
+// Lock both resumes in ID order so concurrent resolutions cannot deadlock.
+func (q *Queries) LockResumePairForUpdate(ctx context.Context, arg LockResumePairForUpdateParams) ([]LockResumePairForUpdateRow, error) {
+	rows, err := q.db.Query(ctx, lockResumePairForUpdate, arg.ResumeAID, arg.ResumeBID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LockResumePairForUpdateRow
+	for rows.Next() {
+		var i LockResumePairForUpdateRow
+		if err := rows.Scan(&i.ID, &i.CurrentEloInt, &i.BattlesCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUploadIntentForOwner = `-- name: LockUploadIntentForOwner :one
+select id, owner_user_id, slot, name, industry, yoe_bucket, hide_companies, object_key, expires_at, created_at
+from app.upload_intents
+where id = $1 and owner_user_id = $2
+for update
+`
+
+type LockUploadIntentForOwnerParams struct {
+	ID          pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+// Locks the intent so only one finalize turns it into a resume.
+func (q *Queries) LockUploadIntentForOwner(ctx context.Context, arg LockUploadIntentForOwnerParams) (AppUploadIntent, error) {
+	row := q.db.QueryRow(ctx, lockUploadIntentForOwner, arg.ID, arg.OwnerUserID)
+	var i AppUploadIntent
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerUserID,
+		&i.Slot,
+		&i.Name,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.HideCompanies,
+		&i.ObjectKey,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const markGuestSessionMerged = `-- name: MarkGuestSessionMerged :one
+update app.guest_sessions
+set merged_into_user_id = $1,
+    merged_at = now()
+where id = $2 and merged_into_user_id is null
+returning id, created_at, expires_at, merged_into_user_id, merged_at
+`
+
+type MarkGuestSessionMergedParams struct {
+	UserID pgtype.UUID
+	ID     pgtype.UUID
+}
+
+// Only an unmerged session can be merged, so a replayed merge affects no rows.
+func (q *Queries) MarkGuestSessionMerged(ctx context.Context, arg MarkGuestSessionMergedParams) (AppGuestSession, error) {
+	row := q.db.QueryRow(ctx, markGuestSessionMerged, arg.UserID, arg.ID)
+	var i AppGuestSession
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.MergedIntoUserID,
+		&i.MergedAt,
+	)
+	return i, err
+}
+
+const matchHasResumeOwnedBy = `-- name: MatchHasResumeOwnedBy :one
+select exists (
+  select 1
+  from app.resumes
+  where (id = $1 or id = $2) and owner_user_id = $3
+)
+`
+
+type MatchHasResumeOwnedByParams struct {
+	ResumeAID   pgtype.UUID
+	ResumeBID   pgtype.UUID
+	OwnerUserID pgtype.UUID
+}
+
+func (q *Queries) MatchHasResumeOwnedBy(ctx context.Context, arg MatchHasResumeOwnedByParams) (bool, error) {
+	row := q.db.QueryRow(ctx, matchHasResumeOwnedBy, arg.ResumeAID, arg.ResumeBID, arg.OwnerUserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const nextResumeVersion = `-- name: NextResumeVersion :one
+select (coalesce(max(version), 0) + 1)::integer as next_version
+from app.resume_versions
+where resume_id = $1
+`
+
+func (q *Queries) NextResumeVersion(ctx context.Context, resumeID pgtype.UUID) (int32, error) {
+	row := q.db.QueryRow(ctx, nextResumeVersion, resumeID)
+	var next_version int32
+	err := row.Scan(&next_version)
+	return next_version, err
+}
+
+const pairCandidates = `-- name: PairCandidates :one
+
+
+with seed as (
+  select id, current_elo_int
+  from app.resumes
+  where industry = $1 and yoe_bucket = $2 and image_ready and not in_flight
+    and owner_user_id is distinct from $3::uuid
+  order by coalesce(last_matched_at, '-infinity') asc
+  limit 1
+  for update skip locked
+)
+, down as (
+  select id, current_elo_int
+  from app.resumes
+  where industry = $1 and yoe_bucket = $2 and image_ready and not in_flight
+    and owner_user_id is distinct from $3::uuid
+    and id <> (select id from seed)
+    and current_elo_int <= (select current_elo_int from seed)
+  order by current_elo_int desc, id
+  limit 1
+  for update skip locked
+)
+, up as (
+  select id, current_elo_int
+  from app.resumes
+  where industry = $1 and yoe_bucket = $2 and image_ready and not in_flight
+    and owner_user_id is distinct from $3::uuid
+    and id <> (select id from seed)
+    and current_elo_int >= (select current_elo_int from seed)
+  order by current_elo_int asc, id
+  limit 1
+  for update skip locked
+)
+select
+  seed.id as seed_id,
+  seed.current_elo_int as seed_elo,
+  down.id as down_id,
+  down.current_elo_int as down_elo,
+  up.id as up_id,
+  up.current_elo_int as up_elo
+from seed
+left join down on true
+left join up on true
+`
+
+type PairCandidatesParams struct {
+	Industry           string
+	YoeBucket          string
+	ExcludeOwnerUserID pgtype.UUID
+}
+
+type PairCandidatesRow struct {
+	SeedID  pgtype.UUID
+	SeedElo int32
+	DownID  pgtype.UUID
+	DownElo pgtype.Int4
+	UpID    pgtype.UUID
+	UpElo   pgtype.Int4
+}
+
+// --------------------- END OF RESUME RELATED QUERIES ----------------------------------------
+// --------------------- START OF MATCHMAKING RELATED QUERIES ----------------------------------------
+// Pick a seed (least recently matched) and its nearest neighbours by Elo on
+// either side. Rows are locked with SKIP LOCKED so concurrent callers never
+// receive the same resume. The voter's own resumes are never candidates; guests
+// pass a null owner and see every resume.
+func (q *Queries) PairCandidates(ctx context.Context, arg PairCandidatesParams) (PairCandidatesRow, error) {
+	row := q.db.QueryRow(ctx, pairCandidates, arg.Industry, arg.YoeBucket, arg.ExcludeOwnerUserID)
+	var i PairCandidatesRow
+	err := row.Scan(
+		&i.SeedID,
+		&i.SeedElo,
+		&i.DownID,
+		&i.DownElo,
+		&i.UpID,
+		&i.UpElo,
+	)
+	return i, err
+}
+
+const recordGuestSelfVotes = `-- name: RecordGuestSelfVotes :execrows
+insert into app.vote_violations (match_id, user_id, guest_session_id, reason)
+select m.id, $1::uuid, m.decided_by_guest_session_id, 'self_vote'
+from app.matches m
+where m.decided_by_guest_session_id = $2 and m.decided_by_user_id is null
+  and exists (
+    select 1
+    from app.resumes r
+    where r.id in (m.resume_a_id, m.resume_b_id) and r.owner_user_id = $1::uuid
+  )
+`
+
+type RecordGuestSelfVotesParams struct {
+	UserID         pgtype.UUID
+	GuestSessionID pgtype.UUID
+}
+
+// Audits votes the session cast on the user's own resumes, which the vote endpoint
+// can't catch while the voter is anonymous. AttributeGuestVotesToUser leaves them out.
+func (q *Queries) RecordGuestSelfVotes(ctx context.Context, arg RecordGuestSelfVotesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordGuestSelfVotes, arg.UserID, arg.GuestSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const refreshLeaderboard = `-- name: RefreshLeaderboard :exec
+refresh materialized view app.leaderboard
+`
+
+// Blocks readers; only used for the first population.
+func (q *Queries) RefreshLeaderboard(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, refreshLeaderboard)
+	return err
+}
+
+const refreshLeaderboardConcurrently = `-- name: RefreshLeaderboardConcurrently :exec
+refresh materialized view concurrently app.leaderboard
+`
+
+func (q *Queries) RefreshLeaderboardConcurrently(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, refreshLeaderboardConcurrently)
+	return err
+}
+
+const releaseResumesInFlight = `-- name: ReleaseResumesInFlight :execrows
+update app.resumes
+set in_flight = false
+where id = any($1::uuid[])
+`
+
+func (q *Queries) ReleaseResumesInFlight(ctx context.Context, resumeIds []pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, releaseResumesInFlight, resumeIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const requeueStuckResumeJobs = `-- name: RequeueStuckResumeJobs :execrows
+with stuck as (
+  select s.id,
+    row_number() over (partition by s.resume_id, s.kind order by s.created_at desc, s.id desc) > 1
+      or exists (
+        select 1 from app.resume_jobs q
+        where q.resume_id = s.resume_id and q.kind = s.kind and q.state = 'queued'
+      ) as superseded
+  from (
+    select id, resume_id, kind, created_at
+    from app.resume_jobs
+    where state = 'running' and locked_at < $1
+    for update skip locked
+  ) s
+)
+update app.resume_jobs j
+set state = case when stuck.superseded then 'failed' else 'queued' end,
+    locked_at = null,
+    run_at = now(),
+    last_error = case when stuck.superseded then 'superseded by a newer job' else j.last_error end,
+    finished_at = case when stuck.superseded then now() end
+from stuck
+where j.id = stuck.id
+`
+
+// Jobs whose worker died mid-run. The newest per resume and kind is requeued; the
+// rest, and any with a queued job already waiting, are closed as failed so
+// resume_jobs_pending_unique holds.
+func (q *Queries) RequeueStuckResumeJobs(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueStuckResumeJobs, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const resolveMatch = `-- name: ResolveMatch :one
+update app.matches
+set resolved_at = now(),
+    winner_resume_id = $2,
+    loser_resume_id = $3,
+    decided_by_user_id = $4,
+    k_factor_used = $5,
+    delta_a = $6,
+    delta_b = $7,
+    outcome = $8,
+    decided_by_guest_session_id = $9,
+    state = 'resolved'
+where id = $1 and state = 'created'
+returning id, resume_a_id, resume_b_id, industry, yoe_bucket, created_at, issued_to_user_id, issued_to_guest_session_id, resolved_at, winner_resume_id, loser_resume_id, decided_by_user_id, decided_by_guest_session_id, k_factor_used, delta_a, delta_b, state, outcome
+`
+
+type ResolveMatchParams struct {
+	ID                      pgtype.UUID
+	WinnerResumeID          pgtype.UUID
+	LoserResumeID           pgtype.UUID
+	DecidedByUserID         pgtype.UUID
+	KFactorUsed             pgtype.Int4
+	DeltaA                  pgtype.Int4
+	DeltaB                  pgtype.Int4
+	Outcome                 pgtype.Text
+	DecidedByGuestSessionID pgtype.UUID
+}
+
+// Only matches still in 'created' can be resolved or cancelled, so a second
+// call affects no rows instead of applying the result twice.
+func (q *Queries) ResolveMatch(ctx context.Context, arg ResolveMatchParams) (AppMatch, error) {
+	row := q.db.QueryRow(ctx, resolveMatch,
+		arg.ID,
+		arg.WinnerResumeID,
+		arg.LoserResumeID,
+		arg.DecidedByUserID,
+		arg.KFactorUsed,
+		arg.DeltaA,
+		arg.DeltaB,
+		arg.Outcome,
+		arg.DecidedByGuestSessionID,
+	)
+	var i AppMatch
+	err := row.Scan(
+		&i.ID,
+		&i.ResumeAID,
+		&i.ResumeBID,
+		&i.Industry,
+		&i.YoeBucket,
 		&i.CreatedAt,
-		&i.PdfStorageKey,
-		&i.PdfSizeBytes,
-		&i.PdfMime,
-		&i.ImageKeyPrefix,
-		&i.PageCount,
-		&i.ImageReady,
-		&i.Slot,
+		&i.IssuedToUserID,
+		&i.IssuedToGuestSessionID,
+		&i.ResolvedAt,
+		&i.WinnerResumeID,
+		&i.LoserResumeID,
+		&i.DecidedByUserID,
+		&i.DecidedByGuestSessionID,
+		&i.KFactorUsed,
+		&i.DeltaA,
+		&i.DeltaB,
+		&i.State,
+		&i.Outcome,
 	)
 	return i, err
 }
 
-const deleteResumeByIDForOwner = `-- name: DeleteResumeByIDForOwner :exec
-delete from app.resumes
-where id = $1 and owner_user_id = $2
+const retryResumeJob = `-- name: RetryResumeJob :exec
+update app.resume_jobs j
+set state = case when s.superseded then 'failed' else 'queued' end,
+    locked_at = null,
+    run_at = $1,
+    last_error = $2,
+    finished_at = case when s.superseded then now() end
+from (
+  select exists (
+    select 1 from app.resume_jobs q
+    where q.resume_id = r.resume_id and q.kind = r.kind and q.state = 'queued'
+  ) as superseded
+  from app.resume_jobs r
+  where r.id = $3
+) s
+where j.id = $3
 `
 
-type DeleteResumeByIDForOwnerParams struct {
-	ID          pgtype.UUID
-	OwnerUserID pgtype.UUID
+type RetryResumeJobParams struct {
+	RunAt     pgtype.Timestamptz
+	LastError pgtype.Text
+	ID        pgtype.UUID
 }
 
-// Delete ---------------------------------------------------------------
-func (q *Queries) DeleteResumeByIDForOwner(ctx context.Context, arg DeleteResumeByIDForOwnerParams) error {
-	_, err := q.db.Exec(ctx, deleteResumeByIDForOwner, arg.ID, arg.OwnerUserID)
+// A job queued for the same resume since this one was claimed already covers the
+// retry, so this one is closed as failed instead of tripping resume_jobs_pending_unique.
+func (q *Queries) RetryResumeJob(ctx context.Context, arg RetryResumeJobParams) error {
+	_, err := q.db.Exec(ctx, retryResumeJob, arg.RunAt, arg.LastError, arg.ID)
 	return err
 }
 
-const findFreeSlotForOwner = `-- name: FindFreeSlotForOwner :one
+const retryResumeTombstone = `-- name: RetryResumeTombstone :exec
+update app.resume_tombstones
+set attempts = attempts + 1,
+    run_at = $1,
+    last_error = $2
+where id = $3
+`
 
-with slots as (select unnest(array[1,2,3])::smallint as slot)
-select s.slot
-from slots s
-left join app.resumes r
-  on r.owner_user_id = $1 and r.slot = s.slot
-where r.id is null
-order by s.slot
-limit 1
+type RetryResumeTombstoneParams struct {
+	RunAt     pgtype.Timestamptz
+	LastError pgtype.Text
+	ID        pgtype.UUID
+}
+
+func (q *Queries) RetryResumeTombstone(ctx context.Context, arg RetryResumeTombstoneParams) error {
+	_, err := q.db.Exec(ctx, retryResumeTombstone, arg.RunAt, arg.LastError, arg.ID)
+	return err
+}
+
+const reverseGuestSelfVotes = `-- name: ReverseGuestSelfVotes :one
+with reversed as (
+  update app.matches m
+  set state = 'cancelled'
+  where m.decided_by_guest_session_id = $1 and m.decided_by_user_id is null
+    and m.state = 'resolved'
+    and exists (
+      select 1
+      from app.resumes r
+      where r.id in (m.resume_a_id, m.resume_b_id) and r.owner_user_id = $2::uuid
+    )
+  returning m.resume_a_id, m.resume_b_id, m.delta_a, m.delta_b
+)
+, deltas as (
+  select resume_id, sum(delta)::integer as delta, count(*)::integer as battles
+  from (
+    select resume_a_id as resume_id, delta_a as delta from reversed
+    union all
+    select resume_b_id, delta_b from reversed
+  ) d
+  group by resume_id
+)
+, restored as (
+  update app.resumes r
+  set current_elo_int = r.current_elo_int - d.delta,
+      battles_count = greatest(r.battles_count - d.battles, 0)
+  from deltas d
+  where r.id = d.resume_id
+  returning r.id
+)
+select count(*) as reversed
+from reversed
 `
 
-// --------------------- START OF RESUME RELATED QUERIES ----------------------------------------
-// Allocate a free slot (1..3) for an owner -------------------------------
-func (q *Queries) FindFreeSlotForOwner(ctx context.Context, ownerUserID pgtype.UUID) (int16, error) {
-	row := q.db.QueryRow(ctx, findFreeSlotForOwner, ownerUserID)
-	var slot int16
-	err := row.Scan(&slot)
-	return slot, err
+type ReverseGuestSelfVotesParams struct {
+	GuestSessionID pgtype.UUID
+	UserID         pgtype.UUID
 }
 
-const getResumeByID = `-- name: GetResumeByID :one
-select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
-from app.resumes
-where id = $1
+// Undoes the rating changes of votes the session cast on the user's own resumes,
+// for both sides of each match, and voids those matches. Returns how many matches
+// were reversed.
+func (q *Queries) ReverseGuestSelfVotes(ctx context.Context, arg ReverseGuestSelfVotesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, reverseGuestSelfVotes, arg.GuestSessionID, arg.UserID)
+	var reversed int64
+	err := row.Scan(&reversed)
+	return reversed, err
+}
+
+const rewriteResumePdfStorageKey = `-- name: RewriteResumePdfStorageKey :exec
+update app.resumes
+set pdf_storage_key = $1
+where id = $2 and pdf_storage_key = $3
 `
 
-// Read ------------------------------------------------------------------
-func (q *Queries) GetResumeByID(ctx context.Context, id pgtype.UUID) (AppResume, error) {
-	row := q.db.QueryRow(ctx, getResumeByID, id)
+type RewriteResumePdfStorageKeyParams struct {
+	NewKey   pgtype.Text
+	ResumeID pgtype.UUID
+	OldKey   pgtype.Text
+}
+
+func (q *Queries) RewriteResumePdfStorageKey(ctx context.Context, arg RewriteResumePdfStorageKeyParams) error {
+	_, err := q.db.Exec(ctx, rewriteResumePdfStorageKey, arg.NewKey, arg.ResumeID, arg.OldKey)
+	return err
+}
+
+const rewriteResumeVersionPdfStorageKey = `-- name: RewriteResumeVersionPdfStorageKey :exec
+update app.resume_versions
+set pdf_storage_key = $1
+where resume_id = $2 and pdf_storage_key = $3
+`
+
+type RewriteResumeVersionPdfStorageKeyParams struct {
+	NewKey   pgtype.Text
+	ResumeID pgtype.UUID
+	OldKey   pgtype.Text
+}
+
+func (q *Queries) RewriteResumeVersionPdfStorageKey(ctx context.Context, arg RewriteResumeVersionPdfStorageKeyParams) error {
+	_, err := q.db.Exec(ctx, rewriteResumeVersionPdfStorageKey, arg.NewKey, arg.ResumeID, arg.OldKey)
+	return err
+}
+
+const setCurrentVersionRenditions = `-- name: SetCurrentVersionRenditions :exec
+update app.resume_versions v
+set image_key_prefix = r.image_key_prefix,
+    image_manifest = r.image_manifest,
+    rendered_hide_companies = $1
+from app.resumes r
+where r.id = $2 and v.resume_id = r.id and v.version = r.current_version
+`
+
+type SetCurrentVersionRenditionsParams struct {
+	RenderedHideCompanies pgtype.Bool
+	ID                    pgtype.UUID
+}
+
+// Keep the live version's row pointing at its latest renditions, rendered with
+// rendered_hide_companies.
+func (q *Queries) SetCurrentVersionRenditions(ctx context.Context, arg SetCurrentVersionRenditionsParams) error {
+	_, err := q.db.Exec(ctx, setCurrentVersionRenditions, arg.RenderedHideCompanies, arg.ID)
+	return err
+}
+
+const setResumeCurrentVersion = `-- name: SetResumeCurrentVersion :one
+update app.resumes
+set current_version = $1,
+    pdf_storage_key = $2,
+    pdf_size_bytes = $3,
+    page_count = $4,
+    image_key_prefix = $5,
+    image_manifest = $6,
+    image_ready = $7,
+    processing_state = $8,
+    processing_error = null,
+    current_elo_int = $9,
+    battles_count = $10
+where id = $11
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
+`
+
+type SetResumeCurrentVersionParams struct {
+	Version         int32
+	PdfStorageKey   pgtype.Text
+	PdfSizeBytes    pgtype.Int8
+	PageCount       int16
+	ImageKeyPrefix  pgtype.Text
+	ImageManifest   json.RawMessage
+	ImageReady      bool
+	ProcessingState string
+	CurrentEloInt   int32
+	BattlesCount    int32
+	ID              pgtype.UUID
+}
+
+// Make a version live: copy its files onto the resume and set the rating.
+func (q *Queries) SetResumeCurrentVersion(ctx context.Context, arg SetResumeCurrentVersionParams) (AppResume, error) {
+	row := q.db.QueryRow(ctx, setResumeCurrentVersion,
+		arg.Version,
+		arg.PdfStorageKey,
+		arg.PdfSizeBytes,
+		arg.PageCount,
+		arg.ImageKeyPrefix,
+		arg.ImageManifest,
+		arg.ImageReady,
+		arg.ProcessingState,
+		arg.CurrentEloInt,
+		arg.BattlesCount,
+		arg.ID,
+	)
 	var i AppResume
 	err := row.Scan(
 		&i.ID,
@@ -141,140 +2356,103 @@ func (q *Queries) GetResumeByID(ctx context.Context, id pgtype.UUID) (AppResume,
 		&i.PageCount,
 		&i.ImageReady,
 		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
 	)
 	return i, err
 }
 
-const getResumeByIDForOwner = `-- name: GetResumeByIDForOwner :one
-select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
-from app.resumes
+const setResumeInFlight = `-- name: SetResumeInFlight :exec
+update app.resumes
+set in_flight = $3
 where id = $1 and owner_user_id = $2
 `
 
-type GetResumeByIDForOwnerParams struct {
+type SetResumeInFlightParams struct {
 	ID          pgtype.UUID
 	OwnerUserID pgtype.UUID
+	InFlight    bool
 }
 
-func (q *Queries) GetResumeByIDForOwner(ctx context.Context, arg GetResumeByIDForOwnerParams) (AppResume, error) {
-	row := q.db.QueryRow(ctx, getResumeByIDForOwner, arg.ID, arg.OwnerUserID)
-	var i AppResume
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.OwnerUserID,
-		&i.Industry,
-		&i.YoeBucket,
-		&i.CurrentEloInt,
-		&i.BattlesCount,
-		&i.LastMatchedAt,
-		&i.InFlight,
-		&i.CreatedAt,
-		&i.PdfStorageKey,
-		&i.PdfSizeBytes,
-		&i.PdfMime,
-		&i.ImageKeyPrefix,
-		&i.PageCount,
-		&i.ImageReady,
-		&i.Slot,
-	)
-	return i, err
+func (q *Queries) SetResumeInFlight(ctx context.Context, arg SetResumeInFlightParams) error {
+	_, err := q.db.Exec(ctx, setResumeInFlight, arg.ID, arg.OwnerUserID, arg.InFlight)
+	return err
 }
 
-const listOwnerSlots = `-- name: ListOwnerSlots :many
-select slot
-from app.resumes
-where owner_user_id = $1
-order by slot
+const setResumeInFlightByID = `-- name: SetResumeInFlightByID :exec
+update app.resumes
+set in_flight = $2
+where id = $1
 `
 
-// Convenience ----------------------------------------------------------
-func (q *Queries) ListOwnerSlots(ctx context.Context, ownerUserID pgtype.UUID) ([]int16, error) {
-	rows, err := q.db.Query(ctx, listOwnerSlots, ownerUserID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []int16
-	for rows.Next() {
-		var slot int16
-		if err := rows.Scan(&slot); err != nil {
-			return nil, err
-		}
-		items = append(items, slot)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetResumeInFlightByIDParams struct {
+	ID       pgtype.UUID
+	InFlight bool
 }
 
-const listResumesByOwner = `-- name: ListResumesByOwner :many
-select id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
-from app.resumes
-where owner_user_id = $1
-order by created_at desc, id
-limit $2 offset $3
+func (q *Queries) SetResumeInFlightByID(ctx context.Context, arg SetResumeInFlightByIDParams) error {
+	_, err := q.db.Exec(ctx, setResumeInFlightByID, arg.ID, arg.InFlight)
+	return err
+}
+
+const setResumeProcessingState = `-- name: SetResumeProcessingState :exec
+with updated as (
+  update app.resumes
+  set processing_state = $1,
+      processing_error = $2
+  where id = $3
+  returning id
+)
+select pg_notify('resume_processing', id::text)
+from updated
 `
 
-type ListResumesByOwnerParams struct {
-	OwnerUserID pgtype.UUID
-	Limit       int32
-	Offset      int32
+type SetResumeProcessingStateParams struct {
+	ProcessingState string
+	ProcessingError pgtype.Text
+	ID              pgtype.UUID
 }
 
-func (q *Queries) ListResumesByOwner(ctx context.Context, arg ListResumesByOwnerParams) ([]AppResume, error) {
-	rows, err := q.db.Query(ctx, listResumesByOwner, arg.OwnerUserID, arg.Limit, arg.Offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []AppResume
-	for rows.Next() {
-		var i AppResume
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.OwnerUserID,
-			&i.Industry,
-			&i.YoeBucket,
-			&i.CurrentEloInt,
-			&i.BattlesCount,
-			&i.LastMatchedAt,
-			&i.InFlight,
-			&i.CreatedAt,
-			&i.PdfStorageKey,
-			&i.PdfSizeBytes,
-			&i.PdfMime,
-			&i.ImageKeyPrefix,
-			&i.PageCount,
-			&i.ImageReady,
-			&i.Slot,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+// Every change is announced on the resume_processing channel (payload: resume id)
+// so status streams on any backend instance can push it.
+func (q *Queries) SetResumeProcessingState(ctx context.Context, arg SetResumeProcessingStateParams) error {
+	_, err := q.db.Exec(ctx, setResumeProcessingState, arg.ProcessingState, arg.ProcessingError, arg.ID)
+	return err
 }
 
-const setResumeInFlight = `-- name: SetResumeInFlight :exec
-update app.resumes
-set in_flight = $3
-where id = $1 and owner_user_id = $2
-`
+const snapshotCurrentResumeVersion = `-- name: SnapshotCurrentResumeVersion :exec
 
-type SetResumeInFlightParams struct {
-	ID          pgtype.UUID
-	OwnerUserID pgtype.UUID
-	InFlight    bool
-}
 
-func (q *Queries) SetResumeInFlight(ctx context.Context, arg SetResumeInFlightParams) error {
-	_, err := q.db.Exec(ctx, setResumeInFlight, arg.ID, arg.OwnerUserID, arg.InFlight)
+
+insert into app.resume_versions (
+  resume_id, version, pdf_storage_key, pdf_size_bytes, page_count,
+  image_key_prefix, image_manifest, final_elo_int, final_battles_count
+)
+select id, current_version, pdf_storage_key, pdf_size_bytes, page_count,
+  image_key_prefix, image_manifest, current_elo_int, battles_count
+from app.resumes
+where id = $1
+on conflict (resume_id, version) do update
+set image_key_prefix = excluded.image_key_prefix,
+    image_manifest = excluded.image_manifest,
+    -- Only SetCurrentVersionRenditions knows what new renditions were made with.
+    rendered_hide_companies = case
+      when excluded.image_key_prefix is not distinct from app.resume_versions.image_key_prefix
+      then app.resume_versions.rendered_hide_companies
+    end,
+    final_elo_int = excluded.final_elo_int,
+    final_battles_count = excluded.final_battles_count
+`
+
+// --------------------- END OF RESUME JOB RELATED QUERIES ----------------------------------------
+// --------------------- START OF RESUME VERSION RELATED QUERIES ----------------------------------------
+// Record the live version (files and current rating) in app.resume_versions.
+// Run when a resume is created and again just before it is superseded.
+func (q *Queries) SnapshotCurrentResumeVersion(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, snapshotCurrentResumeVersion, id)
 	return err
 }
 
@@ -283,7 +2461,7 @@ update app.resumes
 set industry = $3,
     yoe_bucket = $4
 where id = $1 and owner_user_id = $2
-returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
 `
 
 type UpdateResumeBucketsParams struct {
@@ -319,6 +2497,54 @@ func (q *Queries) UpdateResumeBuckets(ctx context.Context, arg UpdateResumeBucke
 		&i.PageCount,
 		&i.ImageReady,
 		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
+	)
+	return i, err
+}
+
+const updateResumeHideCompanies = `-- name: UpdateResumeHideCompanies :one
+update app.resumes
+set hide_companies = $3
+where id = $1 and owner_user_id = $2
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
+`
+
+type UpdateResumeHideCompaniesParams struct {
+	ID            pgtype.UUID
+	OwnerUserID   pgtype.UUID
+	HideCompanies bool
+}
+
+func (q *Queries) UpdateResumeHideCompanies(ctx context.Context, arg UpdateResumeHideCompaniesParams) (AppResume, error) {
+	row := q.db.QueryRow(ctx, updateResumeHideCompanies, arg.ID, arg.OwnerUserID, arg.HideCompanies)
+	var i AppResume
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerUserID,
+		&i.Industry,
+		&i.YoeBucket,
+		&i.CurrentEloInt,
+		&i.BattlesCount,
+		&i.LastMatchedAt,
+		&i.InFlight,
+		&i.CreatedAt,
+		&i.PdfStorageKey,
+		&i.PdfSizeBytes,
+		&i.PdfMime,
+		&i.ImageKeyPrefix,
+		&i.PageCount,
+		&i.ImageReady,
+		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
 	)
 	return i, err
 }
@@ -328,7 +2554,7 @@ update app.resumes
 set image_key_prefix = $3,
     image_ready = coalesce($4, image_ready)
 where id = $1 and owner_user_id = $2
-returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
 `
 
 type UpdateResumeImageMetaParams struct {
@@ -364,6 +2590,11 @@ func (q *Queries) UpdateResumeImageMeta(ctx context.Context, arg UpdateResumeIma
 		&i.PageCount,
 		&i.ImageReady,
 		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
 	)
 	return i, err
 }
@@ -372,7 +2603,7 @@ const updateResumeName = `-- name: UpdateResumeName :one
 update app.resumes
 set name = $3
 where id = $1 and owner_user_id = $2
-returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
 `
 
 type UpdateResumeNameParams struct {
@@ -403,6 +2634,11 @@ func (q *Queries) UpdateResumeName(ctx context.Context, arg UpdateResumeNamePara
 		&i.PageCount,
 		&i.ImageReady,
 		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
 	)
 	return i, err
 }
@@ -413,7 +2649,7 @@ set pdf_storage_key = $3,
     pdf_size_bytes = $4,
     pdf_mime = coalesce($5, pdf_mime)
 where id = $1 and owner_user_id = $2
-returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot
+returning id, name, owner_user_id, industry, yoe_bucket, current_elo_int, battles_count, last_matched_at, in_flight, created_at, pdf_storage_key, pdf_size_bytes, pdf_mime, image_key_prefix, page_count, image_ready, slot, hide_companies, processing_state, processing_error, image_manifest, current_version
 `
 
 type UpdateResumePdfMetaParams struct {
@@ -451,6 +2687,50 @@ func (q *Queries) UpdateResumePdfMeta(ctx context.Context, arg UpdateResumePdfMe
 		&i.PageCount,
 		&i.ImageReady,
 		&i.Slot,
+		&i.HideCompanies,
+		&i.ProcessingState,
+		&i.ProcessingError,
+		&i.ImageManifest,
+		&i.CurrentVersion,
 	)
 	return i, err
 }
+
+const updateResumeRenditions = `-- name: UpdateResumeRenditions :one
+update app.resumes r
+set image_key_prefix = $1,
+    image_manifest = $2,
+    image_ready = true
+from (
+  select id, image_key_prefix
+  from app.resumes
+  where id = $3 and pdf_storage_key = $4 and hide_companies = $5
+  for update
+) old
+where r.id = old.id
+returning old.image_key_prefix as previous_image_key_prefix
+`
+
+type UpdateResumeRenditionsParams struct {
+	ImageKeyPrefix pgtype.Text
+	ImageManifest  json.RawMessage
+	ID             pgtype.UUID
+	PdfStorageKey  pgtype.Text
+	HideCompanies  bool
+}
+
+// Swap in a freshly rendered preview; returns the previous prefix so it can be cleaned up.
+// Only applies while the resume still has the PDF and options that were rendered;
+// a render that raced a new upload, version or setting change affects no rows.
+func (q *Queries) UpdateResumeRenditions(ctx context.Context, arg UpdateResumeRenditionsParams) (pgtype.Text, error) {
+	row := q.db.QueryRow(ctx, updateResumeRenditions,
+		arg.ImageKeyPrefix,
+		arg.ImageManifest,
+		arg.ID,
+		arg.PdfStorageKey,
+		arg.HideCompanies,
+	)
+	var previous_image_key_prefix pgtype.Text
+	err := row.Scan(&previous_image_key_prefix)
+	return previous_image_key_prefix, err
+}
@@ -0,0 +1,30 @@
+package feedback_handler
+
+import "time"
+
+type SubmitFeedbackRequest struct {
+	TargetResumeID string   `json:"target_resume_id" binding:"required,uuid"`
+	Text           string   `json:"text" binding:"max=2000"`
+	Tags           []string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=32"`
+	Visibility     string   `json:"visibility" binding:"omitempty,oneof=owner public"`
+}
+
+// Deliberately excludes author_user_id so voters stay anonymous to owners.
+type FeedbackResponse struct {
+	ID         string    `json:"id"`
+	MatchID    string    `json:"match_id"`
+	Text       string    `json:"text"`
+	Tags       []string  `json:"tags"`
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type TagCountResponse struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+type ListFeedbackResponse struct {
+	Feedback  []FeedbackResponse `json:"feedback"`
+	TagCounts []TagCountResponse `json:"tag_counts"`
+}
@@ -0,0 +1,141 @@
+package feedback_handler
+
+import (
+	"errors"
+	"main/service/auth"
+	"main/service/feedback"
+	"main/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Endpoints for leaving feedback after a vote and reading it back as an owner.
+
+type FeedbackHandler struct {
+	feedbackService *feedback.FeedbackService
+	authService     *auth.AuthService
+	log             *zap.Logger
+}
+
+func NewFeedbackHandler(feedbackService *feedback.FeedbackService, authService *auth.AuthService, log *zap.Logger) *FeedbackHandler {
+	if feedbackService == nil || authService == nil || log == nil {
+		panic("feedbackService, authService, and log must be non-nil")
+	}
+	return &FeedbackHandler{feedbackService: feedbackService, authService: authService, log: log}
+}
+
+func (h *FeedbackHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.POST("/matches/:match_id/feedback", h.authService.AuthMiddleware(), h.SubmitFeedback)
+	rg.GET("/resume/:resume_id/feedback", h.authService.AuthMiddleware(), h.ListFeedback)
+}
+
+func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) {
+	matchID, err := utils.ConvertStringToUUID(c.Param("match_id"))
+	if err != nil {
+		h.log.Error("Failed to convert match ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	var req SubmitFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Visibility == "" {
+		req.Visibility = feedback.VisibilityOwner
+	}
+
+	targetResumeID, err := utils.ConvertStringToUUID(req.TargetResumeID)
+	if err != nil {
+		h.log.Error("Failed to convert target resume ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target resume ID"})
+		return
+	}
+
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
+
+	fb, err := h.feedbackService.Submit(c.Request.Context(), matchID, targetResumeID, userID, req.Text, req.Tags, req.Visibility)
+	switch {
+	case errors.Is(err, feedback.ErrMatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	case errors.Is(err, feedback.ErrNotVoter):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, feedback.ErrTargetNotInMatch), errors.Is(err, feedback.ErrEmptyFeedback):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, feedback.ErrDuplicateFeedback):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.log.Error("Failed to submit feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit feedback"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, FeedbackResponse{
+		ID:         fb.ID.String(),
+		MatchID:    fb.MatchID.String(),
+		Text:       fb.Text,
+		Tags:       fb.Tags,
+		Visibility: fb.Visibility,
+		CreatedAt:  fb.CreatedAt.Time,
+	})
+}
+
+func (h *FeedbackHandler) ListFeedback(c *gin.Context) {
+	resumeID, err := utils.ConvertStringToUUID(c.Param("resume_id"))
+	if err != nil {
+		h.log.Error("Failed to convert resume ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resume ID"})
+		return
+	}
+
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
+
+	result, err := h.feedbackService.ListForOwner(c.Request.Context(), resumeID, userID)
+	if errors.Is(err, feedback.ErrResumeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resume not found"})
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list feedback"})
+		return
+	}
+
+	resp := ListFeedbackResponse{
+		Feedback:  make([]FeedbackResponse, 0, len(result.Feedback)),
+		TagCounts: make([]TagCountResponse, 0, len(result.TagCounts)),
+	}
+	for _, fb := range result.Feedback {
+		resp.Feedback = append(resp.Feedback, FeedbackResponse{
+			ID:         fb.ID.String(),
+			MatchID:    fb.MatchID.String(),
+			Text:       fb.Text,
+			Tags:       fb.Tags,
+			Visibility: fb.Visibility,
+			CreatedAt:  fb.CreatedAt.Time,
+		})
+	}
+	for _, tc := range result.TagCounts {
+		resp.TagCounts = append(resp.TagCounts, TagCountResponse{Tag: tc.Tag, Count: tc.Count})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
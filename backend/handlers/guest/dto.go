@@ -0,0 +1,14 @@
+package guest_handler
+
+import "time"
+
+type GuestSessionResponse struct {
+	GuestSessionID string `json:"guest_session_id"`
+	// Send as the X-Guest-Session header when cookies aren't available.
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type MergeGuestSessionResponse struct {
+	MergedVotes int64 `json:"merged_votes"`
+}
@@ -0,0 +1,92 @@
+package guest_handler
+
+import (
+	"errors"
+	"main/service/auth"
+	"main/service/guest"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Endpoints for voting without an account and handing those votes over on sign-in.
+
+type GuestHandler struct {
+	guestService *guest.GuestService
+	authService  *auth.AuthService
+	cookieSecure bool
+	log          *zap.Logger
+}
+
+func NewGuestHandler(guestService *guest.GuestService, authService *auth.AuthService, cookieSecure bool, log *zap.Logger) *GuestHandler {
+	if guestService == nil || authService == nil || log == nil {
+		panic("guestService, authService, and log must be non-nil")
+	}
+	return &GuestHandler{guestService: guestService, authService: authService, cookieSecure: cookieSecure, log: log}
+}
+
+func (h *GuestHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/guest")
+	g.POST("/session", h.CreateSession)
+	g.POST("/merge", h.authService.AuthMiddleware(), h.MergeSession)
+}
+
+// CreateSession starts a guest session. The token is set as an HttpOnly cookie and
+// also returned for clients on another origin, which send it as a header.
+func (h *GuestHandler) CreateSession(c *gin.Context) {
+	session, err := h.guestService.CreateSession(c.Request.Context())
+	if err != nil {
+		h.log.Error("Failed to create guest session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guest session"})
+		return
+	}
+
+	h.setCookie(c, session.Token, int(time.Until(session.ExpiresAt).Seconds()))
+
+	c.JSON(http.StatusCreated, GuestSessionResponse{
+		GuestSessionID: session.ID.String(),
+		Token:          session.Token,
+		ExpiresAt:      session.ExpiresAt,
+	})
+}
+
+// MergeSession attributes the guest session sent with the request to the signed-in
+// user and clears the cookie.
+func (h *GuestHandler) MergeSession(c *gin.Context) {
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
+
+	sessionID, err := h.authService.GuestTokenFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid guest session"})
+		return
+	}
+
+	votes, err := h.guestService.Merge(c.Request.Context(), sessionID, userID)
+	switch {
+	case errors.Is(err, guest.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Guest session not found"})
+		return
+	case errors.Is(err, guest.ErrAlreadyMerged):
+		c.JSON(http.StatusConflict, gin.H{"error": "Guest session belongs to another account"})
+		return
+	case err != nil:
+		h.log.Error("Failed to merge guest session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge guest session"})
+		return
+	}
+
+	h.setCookie(c, "", -1)
+	c.JSON(http.StatusOK, MergeGuestSessionResponse{MergedVotes: votes})
+}
+
+func (h *GuestHandler) setCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.GuestCookieName, value, maxAge, "/", "", h.cookieSecure, true)
+}
@@ -0,0 +1,24 @@
+package leaderboard_handler
+
+import "main/service/image"
+
+type ListLeaderboardRequest struct {
+	Industry  string `form:"industry" binding:"required,min=1,max=40"`
+	YoeBucket string `form:"yoe" binding:"required,min=1,max=40"`
+	Limit     int32  `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor    string `form:"cursor" binding:"omitempty,max=256"`
+}
+
+// Deliberately excludes owner_user_id, pdf_storage_key and anything derived from them.
+type LeaderboardEntryResponse struct {
+	Rank          int                    `json:"rank"`
+	ResumeID      string                 `json:"resume_id"`
+	CurrentEloInt int32                  `json:"current_elo_int"`
+	BattlesCount  int32                  `json:"battles_count"`
+	Pages         []image.PageRenditions `json:"pages"`
+}
+
+type ListLeaderboardResponse struct {
+	Entries    []LeaderboardEntryResponse `json:"entries"`
+	NextCursor string                     `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,74 @@
+package leaderboard_handler
+
+import (
+	"errors"
+	"main/service/image"
+	"main/service/leaderboard"
+	"main/service/spaces"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Public ranking endpoints. Responses never identify resume owners.
+
+const defaultLeaderboardLimit = 50
+
+type LeaderboardHandler struct {
+	leaderboardService *leaderboard.LeaderboardService
+	webpBucket         *spaces.WebpBucket
+	log                *zap.Logger
+}
+
+func NewLeaderboardHandler(leaderboardService *leaderboard.LeaderboardService, webpBucket *spaces.WebpBucket, log *zap.Logger) *LeaderboardHandler {
+	if leaderboardService == nil || webpBucket == nil || log == nil {
+		panic("leaderboardService, webpBucket, and log must be non-nil")
+	}
+	return &LeaderboardHandler{leaderboardService: leaderboardService, webpBucket: webpBucket, log: log}
+}
+
+func (h *LeaderboardHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/leaderboards")
+	g.GET("", h.ListLeaderboard)
+}
+
+func (h *LeaderboardHandler) ListLeaderboard(c *gin.Context) {
+	var req ListLeaderboardRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultLeaderboardLimit
+	}
+
+	page, err := h.leaderboardService.List(c.Request.Context(), req.Industry, req.YoeBucket, req.Limit, req.Cursor)
+	if errors.Is(err, leaderboard.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to list leaderboard", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list leaderboard"})
+		return
+	}
+
+	resp := ListLeaderboardResponse{
+		Entries:    make([]LeaderboardEntryResponse, 0, len(page.Entries)),
+		NextCursor: page.NextCursor,
+	}
+	for _, e := range page.Entries {
+		resp.Entries = append(resp.Entries, LeaderboardEntryResponse{
+			Rank:          e.Rank,
+			ResumeID:      e.ResumeID.String(),
+			CurrentEloInt: e.CurrentEloInt,
+			BattlesCount:  e.BattlesCount,
+			Pages:         image.WithURLs(e.Pages, h.webpBucket.URL),
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
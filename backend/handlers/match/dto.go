@@ -0,0 +1,5 @@
+package match_handler
+
+type ResolveMatchRequest struct {
+	Outcome string `json:"outcome" binding:"required,oneof=a b draw skip"`
+}
@@ -0,0 +1,83 @@
+package match_handler
+
+import (
+	"errors"
+	"main/service/auth"
+	"main/service/match"
+	"main/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Endpoints for acting on a match once it has been served.
+
+type MatchHandler struct {
+	matchService *match.MatchService
+	authService  *auth.AuthService
+	log          *zap.Logger
+}
+
+func NewMatchHandler(matchService *match.MatchService, authService *auth.AuthService, log *zap.Logger) *MatchHandler {
+	if matchService == nil || authService == nil || log == nil {
+		panic("matchService, authService, and log must be non-nil")
+	}
+	return &MatchHandler{matchService: matchService, authService: authService, log: log}
+}
+
+func (h *MatchHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/matches")
+	// Guests can vote too; see GuestHandler.
+	g.POST("/:match_id/resolve", h.authService.OptionalAuthMiddleware(), h.ResolveMatch)
+}
+
+func (h *MatchHandler) ResolveMatch(c *gin.Context) {
+	matchID, err := utils.ConvertStringToUUID(c.Param("match_id"))
+	if err != nil {
+		h.log.Error("Failed to convert match ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match ID"})
+		return
+	}
+
+	var req ResolveMatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voter, ok := h.authService.GetVoter(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in or start a guest session to vote"})
+		return
+	}
+
+	resolved, err := h.matchService.Resolve(c.Request.Context(), matchID, req.Outcome, voter)
+	switch {
+	case errors.Is(err, match.ErrGuestSessionClosed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Guest session is no longer active"})
+		return
+	case errors.Is(err, match.ErrNotIssuedToVoter):
+		c.JSON(http.StatusForbidden, gin.H{"error": "This match was not served to you"})
+		return
+	case errors.Is(err, match.ErrSelfVote):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot vote on your own resume"})
+		return
+	case errors.Is(err, match.ErrMatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	case errors.Is(err, match.ErrMatchNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": "Match has already been resolved"})
+		return
+	case errors.Is(err, match.ErrInvalidOutcome):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.log.Error("Failed to resolve match", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve match"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resolved)
+}
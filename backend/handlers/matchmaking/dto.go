@@ -0,0 +1,20 @@
+package matchmaking_handler
+
+import "main/service/image"
+
+type CreateMatchRequest struct {
+	Industry  string `form:"industry" binding:"required,min=1,max=40"`
+	YoeBucket string `form:"yoe" binding:"required,min=1,max=40"`
+}
+
+// Deliberately excludes anything that identifies the owner.
+type MatchedResumeResponse struct {
+	ResumeID string                 `json:"resume_id"`
+	Pages    []image.PageRenditions `json:"pages"`
+}
+
+type CreateMatchResponse struct {
+	MatchID string                `json:"match_id"`
+	ResumeA MatchedResumeResponse `json:"resume_a"`
+	ResumeB MatchedResumeResponse `json:"resume_b"`
+}
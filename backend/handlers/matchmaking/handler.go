@@ -0,0 +1,79 @@
+package matchmaking_handler
+
+import (
+	"errors"
+	"main/service/auth"
+	"main/service/image"
+	"main/service/matchmaking"
+	"main/service/spaces"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Endpoints for pairing resumes into battles.
+
+type MatchmakingHandler struct {
+	matchmakingService *matchmaking.MatchmakingService
+	webpBucket         *spaces.WebpBucket
+	authService        *auth.AuthService
+	log                *zap.Logger
+}
+
+func NewMatchmakingHandler(matchmakingService *matchmaking.MatchmakingService, webpBucket *spaces.WebpBucket, authService *auth.AuthService, log *zap.Logger) *MatchmakingHandler {
+	if matchmakingService == nil || webpBucket == nil || authService == nil || log == nil {
+		panic("matchmakingService, webpBucket, authService, and log must be non-nil")
+	}
+	return &MatchmakingHandler{matchmakingService: matchmakingService, webpBucket: webpBucket, authService: authService, log: log}
+}
+
+func (h *MatchmakingHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	g := rg.Group("/matchmaking")
+	g.POST("", h.authService.OptionalAuthMiddleware(), h.CreateMatch)
+}
+
+func (h *MatchmakingHandler) CreateMatch(c *gin.Context) {
+	var req CreateMatchRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voter, ok := h.authService.GetVoter(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in or start a guest session to vote"})
+		return
+	}
+
+	match, err := h.matchmakingService.CreateMatch(c.Request.Context(), req.Industry, req.YoeBucket, voter)
+	if errors.Is(err, matchmaking.ErrGuestSessionClosed) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Guest session is no longer active"})
+		return
+	}
+	if errors.Is(err, matchmaking.ErrNoOpponent) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No opponent available"})
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to create match", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create match"})
+		return
+	}
+
+	c.JSON(http.StatusOK, CreateMatchResponse{
+		MatchID: match.ID.String(),
+		ResumeA: h.toResponse(match.ResumeA),
+		ResumeB: h.toResponse(match.ResumeB),
+	})
+}
+
+// Voters only ever get the public previews, never a link to the PDF.
+func (h *MatchmakingHandler) toResponse(r matchmaking.MatchedResume) MatchedResumeResponse {
+	return MatchedResumeResponse{
+		ResumeID: r.ID.String(),
+		Pages:    image.WithURLs(r.Pages, h.webpBucket.URL),
+	}
+}
@@ -0,0 +1,64 @@
+package media_handler
+
+import (
+	"errors"
+	"main/service/spaces"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Serves the public webp bucket when there is no CDN in front of it, i.e. with the
+// disk and memory storage drivers. WEBP_CDN_BASE_URL defaults to this route then.
+
+type MediaHandler struct {
+	webpBucket *spaces.WebpBucket
+	log        *zap.Logger
+}
+
+func NewMediaHandler(webpBucket *spaces.WebpBucket, log *zap.Logger) *MediaHandler {
+	if webpBucket == nil || log == nil {
+		panic("webpBucket and log must be non-nil")
+	}
+	return &MediaHandler{webpBucket: webpBucket, log: log}
+}
+
+func (h *MediaHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/media/*key", h.GetObject)
+}
+
+func (h *MediaHandler) GetObject(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	obj, err := h.webpBucket.HeadObject(c.Request.Context(), key)
+	// No object can live under a key the store refuses.
+	if errors.Is(err, spaces.ErrObjectNotFound) || errors.Is(err, spaces.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to get object metadata", zap.String("key", key), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get object"})
+		return
+	}
+
+	contentType := obj.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(obj.Size, 10))
+	// Keys are content-versioned, same as the CDN's policy.
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+
+	if _, err := h.webpBucket.StreamFileToWriter(c.Request.Context(), key, c.Writer); err != nil {
+		h.log.Error("Failed to stream object", zap.String("key", key), zap.Error(err))
+	}
+}
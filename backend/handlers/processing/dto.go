@@ -0,0 +1,12 @@
+package processing_handler
+
+import "main/service/image"
+
+type StatusResponse struct {
+	ResumeID       string                 `json:"resume_id"`
+	State          string                 `json:"processing_state"`
+	Error          string                 `json:"processing_error,omitempty"`
+	ImageReady     bool                   `json:"image_ready"`
+	ImageKeyPrefix string                 `json:"image_key_prefix"`
+	Pages          []image.PageRenditions `json:"pages"`
+}
@@ -0,0 +1,161 @@
+package processing_handler
+
+import (
+	"errors"
+	"main/service/auth"
+	"main/service/image"
+	"main/service/processing"
+	"main/service/spaces"
+	"main/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+// Endpoints for following a resume through background processing.
+
+const (
+	// Comment lines keep proxies from closing an idle stream.
+	heartbeatInterval = 15 * time.Second
+	// Streams are closed after this long; clients reconnect until the resume is done.
+	maxStreamDuration = 5 * time.Minute
+)
+
+type ProcessingHandler struct {
+	processingService *processing.ProcessingService
+	broker            *processing.Broker
+	webpBucket        *spaces.WebpBucket
+	authService       *auth.AuthService
+	log               *zap.Logger
+}
+
+func NewProcessingHandler(processingService *processing.ProcessingService, broker *processing.Broker, webpBucket *spaces.WebpBucket, authService *auth.AuthService, log *zap.Logger) *ProcessingHandler {
+	if processingService == nil || broker == nil || webpBucket == nil || authService == nil || log == nil {
+		panic("processingService, broker, webpBucket, authService, and log must be non-nil")
+	}
+	return &ProcessingHandler{processingService: processingService, broker: broker, webpBucket: webpBucket, authService: authService, log: log}
+}
+
+func (h *ProcessingHandler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/resume/:resume_id/status", h.authService.AuthMiddleware(), h.GetStatus)
+	rg.GET("/resume/:resume_id/events", h.authService.AuthMiddleware(), h.StreamEvents)
+}
+
+func (h *ProcessingHandler) GetStatus(c *gin.Context) {
+	resumeID, ok := h.resumeID(c)
+	if !ok {
+		return
+	}
+	status, ok := h.loadStatus(c, resumeID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.toStatusResponse(status))
+}
+
+// StreamEvents sends a "status" event with the current state, then another on every
+// change, and ends once the resume is ready or has failed.
+func (h *ProcessingHandler) StreamEvents(c *gin.Context) {
+	resumeID, ok := h.resumeID(c)
+	if !ok {
+		return
+	}
+
+	// Subscribe before loading the first status so a change committed in between still
+	// wakes the loop below. Nothing is streamed unless the caller owns the resume.
+	updates, unsubscribe := h.broker.Subscribe(resumeID.String())
+	defer unsubscribe()
+
+	status, ok := h.loadStatus(c, resumeID)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("status", h.toStatusResponse(status))
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(maxStreamDuration)
+	defer deadline.Stop()
+
+	ctx := c.Request.Context()
+	userID, _ := h.authService.GetUserID(c)
+	for !status.Terminal() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-updates:
+			next, err := h.processingService.GetStatus(ctx, status.ResumeID, userID)
+			if err != nil {
+				if ctx.Err() == nil {
+					h.log.Error("Failed to get processing status", zap.Error(err))
+				}
+				return
+			}
+			status = next
+			c.SSEvent("status", h.toStatusResponse(status))
+			c.Writer.Flush()
+		}
+	}
+}
+
+// resumeID reads the resume ID from the path, writing the error response on failure.
+func (h *ProcessingHandler) resumeID(c *gin.Context) (pgtype.UUID, bool) {
+	resumeID, err := utils.ConvertStringToUUID(c.Param("resume_id"))
+	if err != nil {
+		h.log.Error("Failed to convert resume ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resume ID"})
+		return pgtype.UUID{}, false
+	}
+	return resumeID, true
+}
+
+// loadStatus loads the caller's resume, writing the error response on failure.
+func (h *ProcessingHandler) loadStatus(c *gin.Context, resumeID pgtype.UUID) (*processing.Status, bool) {
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return nil, false
+	}
+
+	status, err := h.processingService.GetStatus(c.Request.Context(), resumeID, userID)
+	if errors.Is(err, processing.ErrResumeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resume not found"})
+		return nil, false
+	}
+	if err != nil {
+		h.log.Error("Failed to get processing status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get processing status"})
+		return nil, false
+	}
+
+	return status, true
+}
+
+func (h *ProcessingHandler) toStatusResponse(s *processing.Status) StatusResponse {
+	return StatusResponse{
+		ResumeID:       s.ResumeID.String(),
+		State:          s.State,
+		Error:          s.Error,
+		ImageReady:     s.ImageReady,
+		ImageKeyPrefix: s.ImageKeyPrefix,
+		Pages:          image.WithURLs(s.Pages, h.webpBucket.URL),
+	}
+}
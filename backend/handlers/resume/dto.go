@@ -1,12 +1,28 @@
 package resume_handler
 
+import (
+	db "main/db/sqlc"
+	"main/service/image"
+	"time"
+)
+
 type RenameResumeRequest struct {
 	ResumeID string `json:"resume_id" binding:"required,uuid4"`
     NewName string  `json:"resume_name" binding:"required,string,min=1,max=40"`
 }
 
-type DeleteResumeRequest struct {
-	ImageKeyPrefix string `json:"image_key_prefix" binding:"required"`
-	PdfStorageKey string `json:"pdf_storage_key" binding:"required"`
+type UpdateAnonymizationRequest struct {
+	// Pointer so an explicit false passes the required check.
+	HideCompanies *bool `json:"hide_companies" binding:"required"`
 }
 
+// ResumeResponse is the stored row plus links for the owner. Field names follow
+// the row's (PascalCase) so existing clients keep working.
+type ResumeResponse struct {
+	db.AppResume
+	// Presigned, short-lived. Empty when the storage driver can't presign.
+	PdfURL          string     `json:"PdfURL,omitempty"`
+	PdfURLExpiresAt *time.Time `json:"PdfURLExpiresAt,omitempty"`
+	// Rendition list with CDN URLs filled in.
+	Pages []image.PageRenditions
+}
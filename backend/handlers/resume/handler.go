@@ -1,14 +1,20 @@
 package resume_handler
 
 import (
+	"context"
+	"errors"
 	db "main/db/sqlc"
 	sqlc "main/db/sqlc"
 	"main/service/auth"
+	"main/service/deletion"
+	"main/service/image"
+	"main/service/resume"
 	"main/service/spaces"
 	"main/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
@@ -16,17 +22,44 @@ import (
 
 type ResumeHandler struct {
 	db *sqlc.Queries
-	webpBucket *spaces.WebpBucket
+	deletionService *deletion.DeletionService
+	resumeService *resume.ResumeService
 	resumeBucket *spaces.ResumeBucket
+	webpBucket *spaces.WebpBucket
 	log *zap.Logger
 	authService *auth.AuthService
 }
 
-func NewResumeHandler(db *sqlc.Queries, webpBucket *spaces.WebpBucket, resumeBucket *spaces.ResumeBucket, log *zap.Logger, authService *auth.AuthService) *ResumeHandler {
-	if db == nil || webpBucket == nil || resumeBucket == nil || log == nil || authService == nil {
-		panic("db, webpBucket, resumeBucket, log, and authService must be non-nil")
+func NewResumeHandler(db *sqlc.Queries, deletionService *deletion.DeletionService, resumeService *resume.ResumeService, resumeBucket *spaces.ResumeBucket, webpBucket *spaces.WebpBucket, log *zap.Logger, authService *auth.AuthService) *ResumeHandler {
+	if db == nil || deletionService == nil || resumeService == nil || resumeBucket == nil || webpBucket == nil || log == nil || authService == nil {
+		panic("db, deletionService, resumeService, resumeBucket, webpBucket, log, and authService must be non-nil")
+	}
+	return &ResumeHandler{db: db, deletionService: deletionService, resumeService: resumeService, resumeBucket: resumeBucket, webpBucket: webpBucket, log: log, authService: authService}
+}
+
+// toResponse attaches a presigned PDF link and CDN preview URLs. Links are only
+// best-effort: a failed presign leaves PdfURL empty and clients use /storage download.
+func (h *ResumeHandler) toResponse(ctx context.Context, resume db.AppResume) ResumeResponse {
+	resp := ResumeResponse{AppResume: resume, Pages: []image.PageRenditions{}}
+
+	if resume.PdfStorageKey.Valid && resume.PdfStorageKey.String != "" {
+		url, expiresAt, err := h.resumeBucket.PresignedURL(ctx, resume.PdfStorageKey.String, resume.Name+".pdf")
+		switch {
+		case err == nil:
+			resp.PdfURL = url
+			resp.PdfURLExpiresAt = &expiresAt
+		case !errors.Is(err, spaces.ErrPresignUnsupported):
+			h.log.Warn("Failed to presign resume PDF", zap.String("resume_id", resume.ID.String()), zap.Error(err))
+		}
+	}
+
+	pages, err := image.ParseManifest(resume.ImageManifest)
+	if err != nil {
+		h.log.Warn("Failed to parse image manifest", zap.String("resume_id", resume.ID.String()), zap.Error(err))
+		return resp
 	}
-	return &ResumeHandler{db: db, webpBucket: webpBucket, resumeBucket: resumeBucket, log: log, authService: authService}
+	resp.Pages = image.WithURLs(pages, h.webpBucket.URL)
+	return resp
 }
 
 func (h *ResumeHandler) RegisterRoutes(rg *gin.RouterGroup) {
@@ -34,6 +67,7 @@ func (h *ResumeHandler) RegisterRoutes(rg *gin.RouterGroup) {
 	g.PUT("", h.authService.AuthMiddleware(), h.RenameResume)
 	g.GET("", h.authService.AuthMiddleware(), h.GetResumes)
 	g.DELETE("/:resume_id", h.authService.AuthMiddleware(), h.DeleteResume)
+	g.PUT("/:resume_id/anonymization", h.authService.AuthMiddleware(), h.UpdateAnonymization)
 }
 
 func (h *ResumeHandler) RenameResume(c *gin.Context) {
@@ -72,7 +106,7 @@ func (h *ResumeHandler) RenameResume(c *gin.Context) {
 	}
 
 
-	c.JSON(http.StatusOK, resume)
+	c.JSON(http.StatusOK, h.toResponse(c.Request.Context(), resume))
 }
 
 func (h *ResumeHandler) GetResumes(c *gin.Context) {
@@ -95,14 +129,16 @@ func (h *ResumeHandler) GetResumes(c *gin.Context) {
 		return
 	}
 	
-	if resumes == nil {
-		c.JSON(http.StatusOK, []db.AppResume{})
-		return
+	resp := make([]ResumeResponse, 0, len(resumes))
+	for _, resume := range resumes {
+		resp = append(resp, h.toResponse(c.Request.Context(), resume))
 	}
-	
-	c.JSON(http.StatusOK, resumes)
+
+	c.JSON(http.StatusOK, resp)
 }
 
+// DeleteResume removes the resume row and all of its stored files. Keys are resolved
+// server-side; anything the buckets fail to delete is retried in the background.
 func (h *ResumeHandler) DeleteResume(c *gin.Context) {
 	resumeId := c.Param("resume_id")
 	if resumeId == "" {
@@ -118,44 +154,65 @@ func (h *ResumeHandler) DeleteResume(c *gin.Context) {
 		return
 	}
 
-	var req DeleteResumeRequest
-	if err := c.ShouldBind(&req); err != nil {
-		h.log.Error("Failed to bind request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
 	userID, ok := h.authService.GetUserID(c)
 	if !ok {
 		h.log.Error("Failed to get user ID")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
 		return
 	}
-	
-	err = h.db.DeleteResumeByIDForOwner(c.Request.Context(), db.DeleteResumeByIDForOwnerParams{
-		ID: resumeID,
-		OwnerUserID: userID,
-	})
 
+	err = h.deletionService.DeleteResume(c.Request.Context(), resumeID, userID)
+	if errors.Is(err, deletion.ErrResumeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resume not found"})
+		return
+	}
+	if errors.Is(err, deletion.ErrResumeInMatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Resume is in an open match, try again shortly"})
+		return
+	}
 	if err != nil {
 		h.log.Error("Failed to delete resume", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete resume"})
 		return
 	}
 
-	err = h.resumeBucket.DeleteResume(c.Request.Context(), req.PdfStorageKey)
+	c.JSON(http.StatusOK, gin.H{"message": "Resume deleted successfully"})
+}
+
+// UpdateAnonymization changes the owner's masking options and queues a re-render
+// from the stored PDF. The old preview stays live until the new one is uploaded.
+func (h *ResumeHandler) UpdateAnonymization(c *gin.Context) {
+	resumeID, err := utils.ConvertStringToUUID(c.Param("resume_id"))
 	if err != nil {
-		h.log.Error("Failed to delete resume from resume bucket", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete resume from resume bucket"})
+		h.log.Error("Failed to convert resume ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resume ID"})
+		return
+	}
+
+	var req UpdateAnonymizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.webpBucket.DeleteWebp(c.Request.Context(), req.ImageKeyPrefix)
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
+
+	updated, err := h.resumeService.UpdateHideCompanies(c.Request.Context(), resumeID, userID, *req.HideCompanies)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resume not found"})
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to delete resume from webp bucket", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete resume from webp bucket"})
+		h.log.Error("Failed to update resume anonymization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resume"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Resume deleted successfully"})
+	c.JSON(http.StatusAccepted, h.toResponse(c.Request.Context(), *updated))
 }
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"main/service/spaces"
 	"mime/multipart"
+	"time"
 )
 
 type UploadResumeRequest struct {
@@ -9,5 +11,31 @@ type UploadResumeRequest struct {
     ResumeName string                `form:"resume_name" binding:"required,alphanum,min=1,max=40"`
     Industry   string                `form:"industry" binding:"required,alphanum,min=1,max=40"`
     YoeBucket  string                `form:"yoe_bucket" binding:"required,min=1,max=40"`
+    // Mask employer names in the public previews.
+    HideCompanies bool               `form:"hide_companies"`
 }
 
+
+// Same fields as UploadResumeRequest, minus the file.
+type UploadIntentRequest struct {
+	ResumeName    string `json:"resume_name" binding:"required,alphanum,min=1,max=40"`
+	Industry      string `json:"industry" binding:"required,alphanum,min=1,max=40"`
+	YoeBucket     string `json:"yoe_bucket" binding:"required,min=1,max=40"`
+	HideCompanies bool   `json:"hide_companies"`
+}
+
+// The browser POSTs Upload.Fields and then the file (as "file") to Upload.URL, then
+// calls /storage/:resume_id/finalize.
+type UploadIntentResponse struct {
+	ResumeID    string                `json:"resume_id"`
+	Slot        int16                 `json:"slot"`
+	Upload      *spaces.PresignedPost `json:"upload"`
+	ExpiresAt   time.Time             `json:"expires_at"`
+	MaxSize     int64                 `json:"max_size"`
+	ContentType string                `json:"content_type"`
+}
+
+type DownloadURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
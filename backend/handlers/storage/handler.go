@@ -3,17 +3,20 @@ package storage
 // A lone endpoint for uploading resumes.
 
 import (
+	"errors"
 	"fmt"
 	"main/service/auth"
 	"main/service/image"
+	"main/service/processing"
 	"main/service/resume"
 	"main/service/spaces"
+	"main/service/upload"
+	"main/utils"
 	"mime"
 	"net/http"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 )
@@ -21,22 +24,29 @@ import (
 type StorageHandler struct {
 	ResumeBucket *spaces.ResumeBucket
 	ResumeService *resume.ResumeService
-	ImageService *image.ImageService
+	UploadService *upload.UploadService
 	authService *auth.AuthService
 	log *zap.Logger
 }
 
-func NewStorageHandler(resumeBucket *spaces.ResumeBucket, resumeService *resume.ResumeService, authService *auth.AuthService, imageService *image.ImageService, log *zap.Logger) *StorageHandler {
-	if resumeBucket == nil || resumeService == nil || authService == nil || imageService == nil || log == nil {
-		panic("resumeBucket, resumeService, authService, and log must be non-nil")
+func NewStorageHandler(resumeBucket *spaces.ResumeBucket, resumeService *resume.ResumeService, authService *auth.AuthService, uploadService *upload.UploadService, log *zap.Logger) *StorageHandler {
+	if resumeBucket == nil || resumeService == nil || authService == nil || uploadService == nil || log == nil {
+		panic("resumeBucket, resumeService, authService, uploadService, and log must be non-nil")
 	}
-	return &StorageHandler{ResumeBucket: resumeBucket, ResumeService: resumeService, authService: authService, ImageService: imageService, log: log}
+	return &StorageHandler{ResumeBucket: resumeBucket, ResumeService: resumeService, authService: authService, UploadService: uploadService, log: log}
 }
 
-func (h *StorageHandler) RegisterRoutes(rg *gin.RouterGroup) {
+// RegisterRoutes mounts the routes that accept a new file on uploads and the rest on rg,
+// so the two can sit behind different rate limits.
+func (h *StorageHandler) RegisterRoutes(rg, uploads *gin.RouterGroup) {
+	u := uploads.Group("/storage")
+	u.POST("", h.authService.AuthMiddleware(), h.UploadResume)
+	u.POST("/upload-intent", h.authService.AuthMiddleware(), h.CreateUploadIntent)
+	u.POST("/:resume_id/finalize", h.authService.AuthMiddleware(), h.FinalizeUpload)
+
 	g := rg.Group("/storage")
-	g.POST("", h.authService.AuthMiddleware(), h.UploadResume)
 	g.GET("/:resume_id/download", h.authService.AuthMiddleware(), h.DownloadResume)
+	g.GET("/:resume_id/url", h.authService.AuthMiddleware(), h.GetDownloadURL)
 }
 
 func (h *StorageHandler) UploadResume(c *gin.Context) {
@@ -79,8 +89,6 @@ func (h *StorageHandler) UploadResume(c *gin.Context) {
 	h.log.Debug("Validating resume file", zap.String("file", file.Filename))
 
 	pdfMetadata, err := h.ResumeBucket.ValidateResumeFile(file)
-	pdfMetadata.StorageKey = pgtype.Text{String: h.ResumeBucket.Prefix(userIDString, req.ResumeName), Valid: true}
-
 	if err != nil {
 		h.log.Error("Invalid resume file", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -89,10 +97,14 @@ func (h *StorageHandler) UploadResume(c *gin.Context) {
 		return
 	}
 
+	// The ID is chosen up front so the object key can be derived from it before the row exists.
+	resumeID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	storageKey := h.ResumeBucket.ContentKey(userIDString, resumeID.String(), pdfMetadata.ContentHash)
+	pdfMetadata.StorageKey = pgtype.Text{String: storageKey, Valid: true}
 
 	h.log.Debug("Uploading file", zap.String("file", file.Filename), zap.Int16("page_count", pdfMetadata.PageCount))
 	
-	err = h.ResumeBucket.UploadResumeAsset(c.Request.Context(), userIDString, req.ResumeName, file)
+	err = h.ResumeBucket.UploadPDF(c.Request.Context(), storageKey, file)
 
 	if err != nil {
 		h.log.Error("Failed to upload resume asset", zap.Error(err))
@@ -101,7 +113,7 @@ func (h *StorageHandler) UploadResume(c *gin.Context) {
 	}
 	
 	imageMetadata := &image.ImageMetadata{ImageReady: false, ImageKeyPrefix: pgtype.Text{String: "", Valid: true}}
-	resume, err := h.ResumeService.CreateResume(c.Request.Context(), userIDString, req.ResumeName, req.Industry, req.YoeBucket, pdfMetadata, imageMetadata)
+	resume, err := h.ResumeService.CreateResume(c.Request.Context(), resumeID, userIDString, req.ResumeName, req.Industry, req.YoeBucket, req.HideCompanies, pdfMetadata, imageMetadata)
 
 	if err != nil {
 		h.log.Error("Failed to create resume", zap.Error(err))
@@ -109,34 +121,145 @@ func (h *StorageHandler) UploadResume(c *gin.Context) {
 		return
 	}
 
-	webpResumeKey, err := h.ImageService.ConvertPDFToWebp(c.Request.Context(), userIDString, resume.ID.String(), file)
+	h.log.Info("Successfully uploaded resume", 
+		zap.String("user_id", userIDString),
+		zap.String("resume_name", req.ResumeName),
+		zap.String("resume_id", resume.ID.String()),
+	)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Resume uploaded successfully", "resume": resume, "processing_status": processing.StateQueued})
+}
+
+// CreateUploadIntent reserves a slot and returns a presigned form for uploading the
+// PDF straight to the bucket. The resume exists only once FinalizeUpload accepts it.
+func (h *StorageHandler) CreateUploadIntent(c *gin.Context) {
+	var req UploadIntentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
+
+	intent, err := h.UploadService.CreateIntent(c.Request.Context(), userID, upload.IntentParams{
+		Name:          req.ResumeName,
+		Industry:      req.Industry,
+		YoeBucket:     req.YoeBucket,
+		HideCompanies: req.HideCompanies,
+	})
+	if errors.Is(err, upload.ErrNoFreeSlot) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No free resume slot"})
+		return
+	}
+	if errors.Is(err, upload.ErrUploadsDisabled) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads are not available"})
+		return
+	}
 	if err != nil {
-		h.log.Error("Failed to convert PDF to WebP",
-			zap.String("user_id", userIDString),
-			zap.String("resume_name", req.ResumeName),
-			zap.Error(err))
+		h.log.Error("Failed to create upload intent", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start upload"})
+		return
 	}
 
-	h.log.Info("Successfully processed resume", 
-		zap.String("user_id", userIDString),
-		zap.String("resume_name", req.ResumeName),
-		zap.String("webp_resume_id", webpResumeKey),
+	c.JSON(http.StatusCreated, UploadIntentResponse{
+		ResumeID:    intent.ResumeID.String(),
+		Slot:        intent.Slot,
+		Upload:      intent.Upload,
+		ExpiresAt:   intent.ExpiresAt,
+		MaxSize:     utils.MAX_RESUME_FILE_SIZE,
+		ContentType: "application/pdf",
+	})
+}
+
+// FinalizeUpload validates a directly uploaded PDF and creates the resume from its
+// intent. Invalid files are deleted and the slot is freed.
+func (h *StorageHandler) FinalizeUpload(c *gin.Context) {
+	resumeID, err := utils.ConvertStringToUUID(c.Param("resume_id"))
+	if err != nil {
+		h.log.Error("Failed to convert resume ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resume ID"})
+		return
+	}
+
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
+
+	resume, err := h.UploadService.Finalize(c.Request.Context(), resumeID, userID)
+	var rejected *upload.RejectedError
+	switch {
+	case errors.As(err, &rejected):
+		h.log.Info("Rejected direct upload", zap.String("resume_id", resumeID.String()), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": rejected.Error()})
+		return
+	case errors.Is(err, upload.ErrIntentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found or expired"})
+		return
+	case errors.Is(err, upload.ErrUploadMissing):
+		c.JSON(http.StatusConflict, gin.H{"error": "File has not been uploaded"})
+		return
+	case err != nil:
+		h.log.Error("Failed to finalize upload", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finalize upload"})
+		return
+	}
+
+	h.log.Info("Successfully finalized resume upload",
+		zap.String("resume_id", resume.ID.String()),
 	)
 
-	imageMetadata.ImageReady = err == nil
-	imageMetadata.ImageKeyPrefix = pgtype.Text{String: webpResumeKey, Valid: true}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Resume uploaded successfully", "resume": resume, "processing_status": processing.StateQueued})
+}
 
-	err = h.ResumeService.UpdateImageMetadataForResume(c.Request.Context(), userIDString, resume.ID.String(), imageMetadata)
+// GetDownloadURL issues a fresh presigned link to the owner's PDF so the browser
+// fetches it straight from the bucket. Drivers that can't presign answer 501 and
+// clients fall back to /download.
+func (h *StorageHandler) GetDownloadURL(c *gin.Context) {
+	userID, ok := h.authService.GetUserIDString(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return
+	}
 
+	resumeID := c.Param("resume_id")
+	resume, err := h.ResumeService.GetResume(c.Request.Context(), userID, resumeID)
 	if err != nil {
-		h.log.Error("Failed to create resume", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.log.Error("Failed to get resume", zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resume not found"})
+		return
+	}
+
+	if !resume.PdfStorageKey.Valid || resume.PdfStorageKey.String == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	url, expiresAt, err := h.ResumeBucket.PresignedURL(c.Request.Context(), resume.PdfStorageKey.String, resume.Name+".pdf")
+	if errors.Is(err, spaces.ErrPresignUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct downloads are not available"})
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to presign resume download", zap.String("resumeID", resumeID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create download link"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Resume uploaded successfully", "resume": resume, })
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, DownloadURLResponse{URL: url, ExpiresAt: expiresAt})
 }
 
+// DownloadResume streams the PDF through the API. Kept for drivers without presigning.
 func (h *StorageHandler) DownloadResume(c *gin.Context) {
 	resumeID := c.Param("resume_id")
 	if resumeID == "" {
@@ -159,12 +282,14 @@ func (h *StorageHandler) DownloadResume(c *gin.Context) {
 		return
 	}
 
-	storageKey := h.ResumeBucket.Prefix(userID, resume.Name)
+	if !resume.PdfStorageKey.Valid || resume.PdfStorageKey.String == "" {
+		h.log.Error("Resume has no stored file", zap.String("resumeID", resumeID))
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	storageKey := resume.PdfStorageKey.String
 	
-	headResult, err := h.ResumeBucket.BucketClient.Client.HeadObject(c.Request.Context(), &s3.HeadObjectInput{
-		Bucket: aws.String(h.ResumeBucket.BucketClient.Name),
-		Key:    aws.String(storageKey),
-	})
+	headResult, err := h.ResumeBucket.HeadObject(c.Request.Context(), storageKey)
 
 	if err != nil {
 		h.log.Error("Failed to get file metadata", 
@@ -177,21 +302,19 @@ func (h *StorageHandler) DownloadResume(c *gin.Context) {
 	filename := resume.Name + ".pdf"
 	c.Header("Content-Disposition", "attachment; filename=\""+filename+"\"")
 	
-	if headResult.ContentType != nil {
-		c.Header("Content-Type", *headResult.ContentType)
+	if headResult.ContentType != "" {
+		c.Header("Content-Type", headResult.ContentType)
 	} else {
 		c.Header("Content-Type", "application/pdf")
 	}
 	
-	if headResult.ContentLength != nil {
-		c.Header("Content-Length", fmt.Sprintf("%d", *headResult.ContentLength))
-	}
+	c.Header("Content-Length", fmt.Sprintf("%d", headResult.Size))
 	
 	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
 	c.Header("Pragma", "no-cache")
 	c.Header("Expires", "0")
 	
-	bytesWritten, err := h.ResumeBucket.BucketClient.StreamFileToWriter(c.Request.Context(), h.ResumeBucket.BucketClient.Name, storageKey, c.Writer)
+	bytesWritten, err := h.ResumeBucket.StreamFileToWriter(c.Request.Context(), storageKey, c.Writer)
 	if err != nil {
 		h.log.Error("Failed to stream file", 
 			zap.String("storageKey", storageKey),
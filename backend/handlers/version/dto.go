@@ -0,0 +1,26 @@
+package version_handler
+
+import (
+	"mime/multipart"
+
+	"main/service/image"
+)
+
+type CreateVersionRequest struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+type VersionResponse struct {
+	Version        int32                  `json:"version"`
+	Current        bool                   `json:"current"`
+	PageCount      int16                  `json:"page_count"`
+	ImageKeyPrefix string                 `json:"image_key_prefix"`
+	Pages          []image.PageRenditions `json:"pages"`
+	FinalElo       *int32                 `json:"final_elo,omitempty"`
+	FinalBattles   *int32                 `json:"final_battles,omitempty"`
+	CreatedAt      string                 `json:"created_at"`
+}
+
+type ListVersionsResponse struct {
+	Versions []VersionResponse `json:"versions"`
+}
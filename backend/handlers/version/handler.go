@@ -0,0 +1,182 @@
+package version_handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/service/auth"
+	"main/service/image"
+	"main/service/processing"
+	"main/service/spaces"
+	"main/service/version"
+	"main/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+// Endpoints for uploading new revisions of a resume and rolling back to old ones.
+
+type VersionHandler struct {
+	versionService *version.VersionService
+	resumeBucket   *spaces.ResumeBucket
+	authService    *auth.AuthService
+	log            *zap.Logger
+}
+
+func NewVersionHandler(versionService *version.VersionService, resumeBucket *spaces.ResumeBucket, authService *auth.AuthService, log *zap.Logger) *VersionHandler {
+	if versionService == nil || resumeBucket == nil || authService == nil || log == nil {
+		panic("versionService, resumeBucket, authService, and log must be non-nil")
+	}
+	return &VersionHandler{versionService: versionService, resumeBucket: resumeBucket, authService: authService, log: log}
+}
+
+// RegisterRoutes mounts version creation on uploads and the rest on rg, so the two
+// can sit behind different rate limits.
+func (h *VersionHandler) RegisterRoutes(rg, uploads *gin.RouterGroup) {
+	rg.GET("/resume/:resume_id/versions", h.authService.AuthMiddleware(), h.ListVersions)
+	uploads.POST("/resume/:resume_id/versions", h.authService.AuthMiddleware(), h.CreateVersion)
+	rg.POST("/resume/:resume_id/versions/:version/rollback", h.authService.AuthMiddleware(), h.Rollback)
+}
+
+// CreateVersion uploads a new PDF into an existing resume and queues it for rendering.
+func (h *VersionHandler) CreateVersion(c *gin.Context) {
+	resumeID, userID, ok := h.resolveIDs(c)
+	if !ok {
+		return
+	}
+
+	var req CreateVersionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.log.Error("Failed to bind request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pdfMetadata, err := h.resumeBucket.ValidateResumeFile(req.File)
+	if err != nil {
+		h.log.Error("Invalid resume file", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resume, err := h.versionService.CreateVersion(c.Request.Context(), resumeID, userID, req.File, pdfMetadata)
+	if err != nil {
+		h.writeError(c, "Failed to create version", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":           "Version uploaded",
+		"resume":            resume,
+		"processing_status": processing.ResumeQueued,
+	})
+}
+
+func (h *VersionHandler) ListVersions(c *gin.Context) {
+	resumeID, userID, ok := h.resolveIDs(c)
+	if !ok {
+		return
+	}
+
+	versions, current, err := h.versionService.List(c.Request.Context(), resumeID, userID)
+	if err != nil {
+		h.writeError(c, "Failed to list versions", err)
+		return
+	}
+
+	resp := ListVersionsResponse{Versions: make([]VersionResponse, 0, len(versions))}
+	for _, v := range versions {
+		pages, err := image.ParseManifest(v.ImageManifest)
+		if err != nil {
+			h.log.Error("Failed to parse image manifest", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list versions"})
+			return
+		}
+		item := VersionResponse{
+			Version:        v.Version,
+			Current:        v.Version == current,
+			PageCount:      v.PageCount,
+			ImageKeyPrefix: v.ImageKeyPrefix.String,
+			Pages:          pages,
+			CreatedAt:      v.CreatedAt.Time.Format(time.RFC3339),
+		}
+		// The current version's snapshot goes stale as it keeps playing.
+		if v.FinalEloInt.Valid && !item.Current {
+			item.FinalElo = &v.FinalEloInt.Int32
+			item.FinalBattles = &v.FinalBattlesCount.Int32
+		}
+		resp.Versions = append(resp.Versions, item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Rollback makes an earlier version live again, re-rendering it if its previews are gone.
+func (h *VersionHandler) Rollback(c *gin.Context) {
+	resumeID, userID, ok := h.resolveIDs(c)
+	if !ok {
+		return
+	}
+
+	target, err := strconv.ParseInt(c.Param("version"), 10, 32)
+	if err != nil || target < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	resume, needsRender, err := h.versionService.Rollback(c.Request.Context(), resumeID, userID, int32(target))
+	if err != nil {
+		h.writeError(c, "Failed to roll back version", err)
+		return
+	}
+
+	status := http.StatusOK
+	if needsRender {
+		status = http.StatusAccepted
+	}
+
+	c.JSON(status, gin.H{
+		"message": "Version restored",
+		"resume":  resume,
+	})
+}
+
+// resolveIDs reads the resume ID from the path and the caller's user ID, writing the
+// error response on failure.
+func (h *VersionHandler) resolveIDs(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	resumeID, err := utils.ConvertStringToUUID(c.Param("resume_id"))
+	if err != nil {
+		h.log.Error("Failed to convert resume ID to UUID", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resume ID"})
+		return pgtype.UUID{}, pgtype.UUID{}, false
+	}
+
+	userID, ok := h.authService.GetUserID(c)
+	if !ok {
+		h.log.Error("Failed to get user ID")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user ID"})
+		return pgtype.UUID{}, pgtype.UUID{}, false
+	}
+
+	return resumeID, userID, true
+}
+
+func (h *VersionHandler) writeError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, version.ErrResumeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resume not found"})
+	case errors.Is(err, version.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+	case errors.Is(err, version.ErrResumeInMatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "Resume is in an open match, try again shortly"})
+	case errors.Is(err, version.ErrAlreadyCurrent):
+		c.JSON(http.StatusConflict, gin.H{"error": "Version is already current"})
+	default:
+		h.log.Error(msg, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
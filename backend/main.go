@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,13 +19,33 @@ import (
 	"go.uber.org/zap"
 
 	db "main/db/sqlc"
+	feedback_handler "main/handlers/feedback"
+	guest_handler "main/handlers/guest"
+	leaderboard_handler "main/handlers/leaderboard"
+	match_handler "main/handlers/match"
+	matchmaking_handler "main/handlers/matchmaking"
+	media_handler "main/handlers/media"
+	processing_handler "main/handlers/processing"
 	resume_handler "main/handlers/resume"
 	"main/handlers/storage"
+	version_handler "main/handlers/version"
 	"main/middleware"
+	"main/service/anonymize"
 	"main/service/auth"
+	"main/service/deletion"
+	"main/service/feedback"
+	"main/service/gc"
+	"main/service/guest"
 	"main/service/image"
+	"main/service/leaderboard"
+	"main/service/match"
+	"main/service/matchmaking"
+	"main/service/processing"
+	"main/service/ratelimit"
 	"main/service/resume"
 	"main/service/spaces"
+	"main/service/upload"
+	"main/service/version"
 	"main/utils"
 )
 
@@ -34,10 +59,17 @@ func main() {
 	logger.Info("Starting server")
 	defer func() { _ = logger.Sync() }()
 
+	// Cancelled on SIGINT/SIGTERM; background workers stop when it is done.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var workers sync.WaitGroup
+
 	corsConfig := cors.Config{
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders: []string{"Origin", "Content-Length", "Content-Type", "Authorization", auth.GuestHeaderName},
+		// Lets the frontend back off when rate limited.
+		ExposeHeaders: []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"},
 	}
 
 	config, err := utils.LoadConfig()
@@ -56,42 +88,194 @@ func main() {
 	logger.Info("Loaded configuration",
 		zap.String("resume_bucket", config.Resume.BucketName),
 		zap.String("webp_bucket", config.Webp.BucketName),
+		zap.String("storage_driver", config.Bucket.Driver),
 		zap.String("endpoint", config.Bucket.BucketEndpoint),
 		zap.String("region", config.Bucket.BucketRegion),
 	)
 
 	router := gin.New()
+	// Rate limits key anonymous callers by ClientIP, so only believe forwarding
+	// headers set by our own proxies.
+	if err := router.SetTrustedProxies(config.RateLimit.TrustedProxies); err != nil {
+		logger.Fatal("Failed to set trusted proxies", zap.Error(err))
+	}
 	router.Use(cors.New(corsConfig))
 	router.Use(middleware.RequestLogger(logger))
 
 	api := router.Group("/api")
 
-	resumeBucket, err := spaces.GetResumeBucket(context.Background(), logger, config)
+	resumeStore, err := spaces.OpenResumeStore(context.Background(), config, logger)
+	if err != nil {
+		logger.Fatal("Failed to open resume bucket", zap.Error(err))
+	}
+	resumeBucket := spaces.NewResumeBucket(resumeStore, config.Resume.PresignTTL, logger)
+
+	webpStore, err := spaces.OpenWebpStore(context.Background(), config, logger)
 	if err != nil {
-		log.Fatal("Failed to create resume fasdfsbucket", zap.Error(err))
+		logger.Fatal("Failed to open webp bucket", zap.Error(err))
 	}
+	webpBucket := spaces.NewWebpBucket(webpStore, config.Webp.CDNBaseURL, logger)
 
-	webpBucket, err := spaces.GetWebpBucket(context.Background(), logger, config)
+	redactor, err := anonymize.NewRedactor(config.Anonymize)
 	if err != nil {
-		log.Fatal("Failed to create webp bucket", zap.Error(err))
+		logger.Fatal("Failed to create redactor", zap.Error(err))
+	}
+
+	imageService := image.NewImageService(logger, webpBucket, redactor, config.Image.Widths)
+
+	var jwks *auth.JWKS
+	if config.Supabase.JWKSURL != "" {
+		jwks = auth.NewJWKS(config.Supabase.JWKSURL, config.Supabase.JWKSRefreshInterval, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			jwks.Run(ctx)
+		}()
+	}
+	var guestTokens *auth.GuestTokens
+	if config.Guest.Enabled {
+		guestTokens = auth.NewGuestTokens(config.Guest.Secret)
+	}
+	authService := auth.NewAuthService(config.Supabase, jwks, guestTokens, logger)
+
+	// limitedAPI returns the /api group behind the rate limit for group.
+	limitedAPI := func(string) *gin.RouterGroup { return api }
+	if config.RateLimit.Enabled {
+		rateLimitStore := ratelimit.NewMemoryStore(logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			rateLimitStore.Run(ctx)
+		}()
+
+		limits := config.RateLimit
+		limiter := ratelimit.NewLimiter(rateLimitStore, map[string]ratelimit.Limit{
+			"default":     ratelimit.PerMinute(limits.Default.PerMinute, int(limits.Default.Burst)),
+			"upload":      ratelimit.PerMinute(limits.Upload.PerMinute, int(limits.Upload.Burst)),
+			"matchmaking": ratelimit.PerMinute(limits.Matchmaking.PerMinute, int(limits.Matchmaking.Burst)),
+			"vote":        ratelimit.PerMinute(limits.Vote.PerMinute, int(limits.Vote.Burst)),
+		})
+		limitedAPI = func(group string) *gin.RouterGroup {
+			return api.Group("", authService.IdentifyMiddleware(), middleware.RateLimit(limiter, group, authService, logger))
+		}
+	}
+
+	processingService := processing.NewProcessingService(db, config.Processing.MaxAttempts)
+	resumeService := resume.NewResumeService(pool, db, processingService)
+
+	workerPool := processing.NewWorkerPool(db, resumeBucket, webpBucket, imageService, config.Processing, config.Leaderboard.Source == leaderboard.SourceMaterialized, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		workerPool.Run(ctx)
+	}()
+
+	broker := processing.NewBroker(pool, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		broker.Run(ctx)
+	}()
+
+	processingHandler := processing_handler.NewProcessingHandler(processingService, broker, webpBucket, authService, logger)
+	processingHandler.RegisterRoutes(limitedAPI("default"))
+
+	if config.Bucket.Driver != spaces.DriverS3 {
+		// No CDN in front of local storage; serve previews from the API.
+		mediaHandler := media_handler.NewMediaHandler(webpBucket, logger)
+		mediaHandler.RegisterRoutes(limitedAPI("default"))
+	}
+
+	uploadService := upload.NewUploadService(pool, db, resumeBucket, processingService, config.Resume.UploadTTL, logger)
+	storageHandler := storage.NewStorageHandler(resumeBucket, resumeService, authService, uploadService, logger)
+	storageHandler.RegisterRoutes(limitedAPI("default"), limitedAPI("upload"))
+
+	deletionService := deletion.NewDeletionService(pool, db, resumeBucket, webpBucket, config.Deletion, logger)
+	deletionRetrier := deletion.NewRetrier(deletionService)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		deletionRetrier.Run(ctx)
+	}()
+
+	resumeHandler := resume_handler.NewResumeHandler(db, deletionService, resumeService, resumeBucket, webpBucket, logger, authService)
+	resumeHandler.RegisterRoutes(limitedAPI("default"))
+
+	versionService := version.NewVersionService(pool, db, resumeBucket, processingService, version.NewEloCarryPolicy(config.Version), logger)
+	versionHandler := version_handler.NewVersionHandler(versionService, resumeBucket, authService, logger)
+	versionHandler.RegisterRoutes(limitedAPI("default"), limitedAPI("upload"))
+
+	matchmakingService := matchmaking.NewMatchmakingService(pool, db, logger)
+	matchmakingHandler := matchmaking_handler.NewMatchmakingHandler(matchmakingService, webpBucket, authService, logger)
+	matchmakingHandler.RegisterRoutes(limitedAPI("matchmaking"))
+
+	leaderboardService := leaderboard.NewLeaderboardService(db, config.Leaderboard.Source)
+	leaderboardHandler := leaderboard_handler.NewLeaderboardHandler(leaderboardService, webpBucket, logger)
+	leaderboardHandler.RegisterRoutes(limitedAPI("default"))
+
+	var resultObserver match.ResultObserver
+	if config.Leaderboard.Source == leaderboard.SourceMaterialized {
+		refresher := leaderboard.NewRefresher(db, webpBucket, config.Leaderboard.RefreshInterval, config.Leaderboard.RefreshAfterMatches, logger)
+		if err := refresher.Refresh(ctx); err != nil {
+			logger.Error("Failed to populate leaderboard", zap.Error(err))
+		}
+		resultObserver = refresher
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			refresher.Run(ctx)
+		}()
 	}
 
-	imageService := image.NewImageService(logger, webpBucket)
+	matchService := match.NewMatchService(pool, db, match.NewTieredKFactorPolicy(config.Elo), resultObserver, config.Guest.VoteWeight, logger)
+	matchHandler := match_handler.NewMatchHandler(matchService, authService, logger)
+	matchHandler.RegisterRoutes(limitedAPI("vote"))
+
+	if config.Guest.Enabled {
+		guestService := guest.NewGuestService(pool, db, guestTokens, config.Guest.SessionTTL, logger)
+		guestHandler := guest_handler.NewGuestHandler(guestService, authService, config.Guest.CookieSecure, logger)
+		guestHandler.RegisterRoutes(limitedAPI("vote"))
+	}
 
-	authService := auth.NewAuthService(config.Supabase.JWTSecret, logger)
-	resumeService := resume.NewResumeService(db)
+	feedbackService := feedback.NewFeedbackService(db)
+	feedbackHandler := feedback_handler.NewFeedbackHandler(feedbackService, authService, logger)
+	feedbackHandler.RegisterRoutes(limitedAPI("vote"))
 
-	storageHandler := storage.NewStorageHandler(resumeBucket, resumeService, authService, imageService, logger)
-	storageHandler.RegisterRoutes(api)
+	if config.GC.Enabled {
+		collector := gc.NewCollector(db, resumeBucket, webpBucket, config.GC.GracePeriod, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			collector.Run(ctx, config.GC.Interval, config.GC.DryRun)
+		}()
+	}
 
-	resumeHandler := resume_handler.NewResumeHandler(db, webpBucket, resumeBucket, logger, authService)
-	resumeHandler.RegisterRoutes(api)
+	reaper := match.NewReaper(pool, db, config.Match.TTL, config.Match.ReaperInterval, logger)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reaper.Run(ctx)
+	}()
 
-	api.GET("/ping", authService.AuthMiddleware(), func(c *gin.Context) {
+	limitedAPI("default").GET("/ping", authService.AuthMiddleware(), func(c *gin.Context) {
 		c.String(http.StatusOK, "pong")
 	})
 
-	if err := router.Run(":8080"); err != nil {
-		log.Fatal(err)
+	srv := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to shut down server cleanly", zap.Error(err))
 	}
+	workers.Wait()
 }
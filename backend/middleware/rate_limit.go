@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"main/service/auth"
+	"main/service/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RateLimit spends a token from the caller's bucket in group, answering 429 with
+// Retry-After once it is empty. Signed-in callers are counted by user ID, everyone
+// else by client IP; run auth.IdentifyMiddleware first so the user is known.
+// If the store fails the request is let through.
+func RateLimit(limiter *ratelimit.Limiter, group string, authService *auth.AuthService, log *zap.Logger) gin.HandlerFunc {
+	limit, limited := limiter.Limit(group)
+	return func(c *gin.Context) {
+		if !limited {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + c.ClientIP()
+		if _, ok := c.Request.Context().Value(auth.UserIDContextKey{}).(string); ok {
+			if userID, ok := authService.GetUserID(c); ok {
+				key = "user:" + userID.String()
+			}
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), group, key)
+		if err != nil {
+			log.Error("Failed to check rate limit", zap.String("group", group), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			log.Warn("Rate limited request", zap.String("group", group), zap.String("key", key), zap.Int("retry_after", retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,143 @@
+package anonymize
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Employer detection for the hide_companies option. Two signals are combined:
+//   - a curated dictionary of well known employers, matched anywhere on the page;
+//   - the layout of experience entries: a line with a date range ("Jan 2020 - Present")
+//     names the employer either on the same line or on the line just above/below it.
+//     Whatever isn't a job title, location or the dates themselves is treated as the employer.
+// False positives only hide more text, which is the safe direction for anonymity.
+
+// knownCompanies is matched case-sensitively so short names like "EY" don't hit ordinary words.
+var knownCompanies = []string{
+	"Google", "Alphabet", "DeepMind", "Meta", "Facebook", "Instagram", "WhatsApp",
+	"Amazon", "AWS", "Apple", "Microsoft", "Netflix", "Uber", "Lyft", "Airbnb",
+	"Stripe", "Shopify", "Salesforce", "Oracle", "IBM", "Intel", "NVIDIA", "Nvidia", "AMD",
+	"Qualcomm", "Cisco", "Adobe", "Twitter", "LinkedIn", "Snap", "Pinterest", "Spotify",
+	"Tesla", "SpaceX", "Palantir", "Databricks", "Snowflake", "Coinbase", "Robinhood",
+	"PayPal", "Dropbox", "Atlassian", "Slack", "Zoom", "Bloomberg", "OpenAI", "Anthropic",
+	"Goldman Sachs", "Morgan Stanley", "JPMorgan", "J.P. Morgan", "JPMorgan Chase",
+	"Citadel", "Jane Street", "Two Sigma", "Hudson River Trading", "DE Shaw", "D. E. Shaw",
+	"McKinsey", "Bain", "Boston Consulting Group", "BCG", "Deloitte", "PwC", "EY", "KPMG", "Accenture",
+	"RBC", "TD Bank", "Scotiabank", "BMO", "CIBC", "Wealthsimple", "Cohere", "Ubisoft", "EA",
+}
+
+var (
+	month     = `(?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?`
+	dateToken = `(?:` + month + `\s+)?(?:\d{1,2}/)?(?:19|20)\d{2}`
+	dateRange = regexp.MustCompile(`(?i)` + dateToken + `\s*(?:-|–|—|to)\s*(?:` + dateToken + `|present|current|now)`)
+
+	// Separators between the parts of an experience heading.
+	separatorRe = regexp.MustCompile(`\s*(?:[,|·•—–@]|\s-\s|\s{2,}|\bat\b)\s*`)
+
+	titleRe = regexp.MustCompile(`(?i)\b(?:engineer|engineering|developer|intern|internship|co-?op|manager|analyst|scientist|designer|` +
+		`consultant|director|lead|architect|specialist|associate|assistant|coordinator|officer|administrator|` +
+		`researcher|research|technician|president|founder|head|programmer|student|teaching|fellow|contractor|` +
+		`representative|accountant|advisor|sde|swe|bachelor|master|b\.?sc|m\.?sc|ph\.?d|gpa|minor|major)\b`)
+	locationRe = regexp.MustCompile(`^(?:[A-Z]{2}|(?i:remote|hybrid|on-?site|usa|canada))$`)
+	bulletRe   = regexp.MustCompile(`^[•·\-*▪●◦]`)
+)
+
+// Lines longer than this next to a date line are body text, not a heading.
+const maxHeadingRunes = 60
+
+func compileDictionary(extra []string) *regexp.Regexp {
+	names := append(append([]string{}, knownCompanies...), extra...)
+	// Longest first so "JPMorgan Chase" wins over "JPMorgan".
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	quoted := make([]string, 0, len(names))
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			quoted = append(quoted, regexp.QuoteMeta(n))
+		}
+	}
+	return regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// companySpans returns the byte ranges of employer names in lines, keyed by line index.
+func companySpans(lines []TextLine, dictionary *regexp.Regexp) map[int][][2]int {
+	spans := map[int][][2]int{}
+	for i, line := range lines {
+		for _, loc := range dictionary.FindAllStringIndex(line.Text, -1) {
+			spans[i] = append(spans[i], [2]int{loc[0], loc[1]})
+		}
+	}
+
+	for i, line := range lines {
+		dates := dateRange.FindAllStringIndex(line.Text, -1)
+		if dates == nil {
+			continue
+		}
+
+		found := employerSegments(line.Text, dates)
+		if len(found) > 0 {
+			spans[i] = append(spans[i], found...)
+			continue
+		}
+
+		// Only a title and dates on this line; the employer is on a neighbouring heading.
+		for _, j := range []int{i - 1, i + 1} {
+			if j < 0 || j >= len(lines) || !isHeading(lines[j].Text) {
+				continue
+			}
+			if found := employerSegments(lines[j].Text, nil); len(found) > 0 {
+				spans[j] = append(spans[j], found...)
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// employerSegments splits a heading on separators and date ranges and returns the
+// parts that aren't titles, locations or dates.
+func employerSegments(text string, dates [][]int) [][2]int {
+	cuts := append(separatorRe.FindAllStringIndex(text, -1), dates...)
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i][0] < cuts[j][0] })
+
+	var out [][2]int
+	start := 0
+	for _, cut := range append(cuts, []int{len(text), len(text)}) {
+		if cut[0] > start {
+			if seg, ok := trimSegment(text, start, cut[0]); ok {
+				out = append(out, seg)
+			}
+		}
+		start = max(start, cut[1])
+	}
+	return out
+}
+
+func trimSegment(text string, start, end int) ([2]int, bool) {
+	seg := text[start:end]
+	trimmed := strings.TrimSpace(seg)
+	if trimmed == "" || titleRe.MatchString(trimmed) || locationRe.MatchString(trimmed) || !hasLetter(trimmed) {
+		return [2]int{}, false
+	}
+	start += strings.Index(seg, trimmed)
+	return [2]int{start, start + len(trimmed)}, true
+}
+
+// isHeading rejects bullets, body text and all-caps section titles like "EXPERIENCE".
+func isHeading(text string) bool {
+	t := strings.TrimSpace(text)
+	if t == "" || utf8.RuneCountInString(t) > maxHeadingRunes || bulletRe.MatchString(t) {
+		return false
+	}
+	if dateRange.MatchString(t) {
+		return false
+	}
+	return !(strings.ToUpper(t) == t && !strings.Contains(t, " "))
+}
+
+func hasLetter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
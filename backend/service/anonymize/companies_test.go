@@ -0,0 +1,97 @@
+package anonymize
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompanySpans(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra []string
+		lines []string
+		// Masked text per line index.
+		want map[int][]string
+	}{
+		{
+			name:  "dictionary names anywhere on the page",
+			lines: []string{"Interned at Google, then Meta"},
+			want:  map[int][]string{0: {"Google", "Meta"}},
+		},
+		{
+			name:  "dictionary prefers the longest name",
+			lines: []string{"Summer analyst with JPMorgan Chase"},
+			want:  map[int][]string{0: {"JPMorgan Chase"}},
+		},
+		{
+			name:  "dictionary is case-sensitive",
+			lines: []string{"Joined a team of ten", "Led the EA Sports launch"},
+			want:  map[int][]string{1: {"EA"}},
+		},
+		{
+			name:  "extra dictionary names",
+			extra: []string{"Initech"},
+			lines: []string{"Reports for Initech leadership"},
+			want:  map[int][]string{0: {"Initech"}},
+		},
+		{
+			name:  "employer on the date line",
+			lines: []string{"Backend Developer, Acme Robotics | Jan 2020 - Present"},
+			want:  map[int][]string{0: {"Acme Robotics"}},
+		},
+		{
+			name:  "location and dates are not the employer",
+			lines: []string{"Acme Robotics, Remote  May 2019 – Aug 2021"},
+			want:  map[int][]string{0: {"Acme Robotics"}},
+		},
+		{
+			name:  "abbreviated month and numeric date",
+			lines: []string{"Vandelay Industries  Sept. 2019 to 03/2021"},
+			want:  map[int][]string{0: {"Vandelay Industries"}},
+		},
+		{
+			name:  "employer on the line above the dates",
+			lines: []string{"Globex Corporation", "Data Analyst  2018 - 2020"},
+			want:  map[int][]string{0: {"Globex Corporation"}},
+		},
+		{
+			name:  "employer on the line below the dates",
+			lines: []string{"Data Analyst  2018 - 2020", "Globex Corporation"},
+			want:  map[int][]string{1: {"Globex Corporation"}},
+		},
+		{
+			name:  "section titles are not headings",
+			lines: []string{"EXPERIENCE", "Data Analyst  2018 - 2020", "Globex Corporation"},
+			want:  map[int][]string{2: {"Globex Corporation"}},
+		},
+		{
+			name:  "bullets are not headings",
+			lines: []string{"• Built reporting pipelines", "Data Analyst  2018 - 2020"},
+			want:  map[int][]string{},
+		},
+		{
+			name:  "no date range means no heading",
+			lines: []string{"Globex Corporation", "Data Analyst"},
+			want:  map[int][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]TextLine, len(tt.lines))
+			for i, text := range tt.lines {
+				lines[i] = TextLine{Text: text}
+			}
+
+			got := map[int][]string{}
+			for i, spans := range companySpans(lines, compileDictionary(tt.extra)) {
+				for _, s := range spans {
+					got[i] = append(got[i], tt.lines[i][s[0]:s[1]])
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("masked %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
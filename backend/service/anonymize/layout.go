@@ -0,0 +1,103 @@
+package anonymize
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// go-fitz does not expose MuPDF's structured text API directly, but its HTML
+// export positions every line absolutely, e.g.
+//
+//	<p style="top:69.0pt;left:72.0pt;line-height:10.0pt"><span style="...;font-size:10.0pt">text</span></p>
+//
+// Glyph widths are not included, so horizontal positions inside a line are
+// estimated from the font size. Each rune gets a range: a box starts where the
+// text would be if every glyph before it were narrow and ends where it would be if
+// every glyph up to its end were wide, so it errs towards hiding too much.
+
+// Bounds on the advance of a glyph as a fraction of the font size. Punctuation and
+// narrow letters such as i and l are about 0.25 em; bold capitals are up to 0.75 em.
+const (
+	minGlyphWidth = 0.25
+	maxGlyphWidth = 0.75
+)
+
+var (
+	lineRe = regexp.MustCompile(`<p style="top:([\d.]+)pt;left:([\d.]+)pt;line-height:([\d.]+)pt[^"]*">(.*?)</p>`)
+	// font-size is not always the last property: colored text (typically links) is
+	// followed by color:#rrggbb.
+	spanRe = regexp.MustCompile(`<span style="[^"]*font-size:([\d.]+)pt[^"]*">(.*?)</span>`)
+	tagRe  = regexp.MustCompile(`<[^>]+>`)
+)
+
+// Rect is an axis-aligned box in PDF points, origin at the top-left of the page.
+type Rect struct {
+	X0, Y0, X1, Y1 float64
+}
+
+// TextLine is one line of text on a page with the estimated x range of each rune.
+type TextLine struct {
+	Text     string
+	Top      float64
+	Left     float64
+	Height   float64
+	FontSize float64
+	// minOffsets[i] and maxOffsets[i] are the leftmost and rightmost x positions where
+	// rune i can start; the final entries are the line end.
+	minOffsets []float64
+	maxOffsets []float64
+}
+
+// Span returns a box that covers runes [start, end) of the line whatever the glyph widths.
+func (l TextLine) Span(start, end int) Rect {
+	return Rect{X0: l.minOffsets[start], Y0: l.Top, X1: l.maxOffsets[end], Y1: l.Top + l.Height}
+}
+
+// Bounds returns the box covering the whole line.
+func (l TextLine) Bounds() Rect {
+	return l.Span(0, len(l.maxOffsets)-1)
+}
+
+// ExtractLines returns the positioned text lines of a page.
+func ExtractLines(doc *fitz.Document, page int) ([]TextLine, error) {
+	out, err := doc.HTML(page, false)
+	if err != nil {
+		return nil, fmt.Errorf("extract text layer: %w", err)
+	}
+	return parseLines(out), nil
+}
+
+func parseLines(doc string) []TextLine {
+	var lines []TextLine
+	for _, m := range lineRe.FindAllStringSubmatch(doc, -1) {
+		top, _ := strconv.ParseFloat(m[1], 64)
+		left, _ := strconv.ParseFloat(m[2], 64)
+		height, _ := strconv.ParseFloat(m[3], 64)
+
+		line := TextLine{Top: top, Left: left, Height: height, minOffsets: []float64{left}, maxOffsets: []float64{left}}
+		var text strings.Builder
+		minX, maxX := left, left
+		for _, s := range spanRe.FindAllStringSubmatch(m[4], -1) {
+			size, _ := strconv.ParseFloat(s[1], 64)
+			line.FontSize = max(line.FontSize, size)
+			for _, r := range html.UnescapeString(tagRe.ReplaceAllString(s[2], "")) {
+				text.WriteRune(r)
+				minX += size * minGlyphWidth
+				maxX += size * maxGlyphWidth
+				line.minOffsets = append(line.minOffsets, minX)
+				line.maxOffsets = append(line.maxOffsets, maxX)
+			}
+		}
+		line.Text = text.String()
+		if strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,149 @@
+package anonymize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"main/utils"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+func TestParseLines(t *testing.T) {
+	doc := `<div id="page0" style="width:612.0pt;height:792.0pt">
+<p style="top:52.8pt;left:72.0pt;line-height:24.0pt"><span style="font-family:Arial,sans-serif;font-size:24.0pt">Jane Doe</span></p>
+<p style="top:94.0pt;left:72.0pt;line-height:10.0pt"><span style="font-family:Arial,sans-serif;font-size:10.0pt;color:#0000ff">jane@example.com</span></p>
+<p style="top:110.0pt;left:72.0pt;line-height:10.0pt"><span style="font-size:10.0pt;font-family:Times,serif;color:#1155cc">https://<b>example.com</b></span><span style="font-size:12.0pt">&amp; more</span></p>
+<p style="top:126.0pt;left:72.0pt;line-height:10.0pt"><span style="font-size:10.0pt">   </span></p>
+</div>`
+
+	lines := parseLines(doc)
+
+	want := []struct {
+		text     string
+		top      float64
+		fontSize float64
+	}{
+		{"Jane Doe", 52.8, 24},
+		{"jane@example.com", 94, 10},
+		{"https://example.com& more", 110, 12},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(lines), len(want), lines)
+	}
+	for i, w := range want {
+		l := lines[i]
+		if l.Text != w.text || l.Top != w.top || l.FontSize != w.fontSize {
+			t.Errorf("line %d = %q top %v size %v, want %q top %v size %v", i, l.Text, l.Top, l.FontSize, w.text, w.top, w.fontSize)
+		}
+		if got := len(l.maxOffsets); got != len([]rune(l.Text))+1 || got != len(l.minOffsets) {
+			t.Errorf("line %d has %d/%d offsets for %d runes", i, len(l.minOffsets), got, len([]rune(l.Text)))
+		}
+	}
+
+	email := lines[1].Bounds()
+	if email.X0 != 72 || email.X1 != 72+16*10*maxGlyphWidth {
+		t.Errorf("email bounds = %+v", email)
+	}
+	// "Jane" set as bold capitals is about 57pt wide at 24pt; the box must cover it.
+	if first := lines[0].Span(0, 4); first.X1-first.X0 < 57 {
+		t.Errorf("name span %+v is narrower than bold capitals", first)
+	}
+	// "Doe" may start anywhere from all-narrow to all-wide glyphs before it.
+	if last := lines[0].Span(5, 8); last.X0 > 72+5*24*minGlyphWidth || last.X1 < 72+8*24*maxGlyphWidth {
+		t.Errorf("name tail span = %+v", last)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	// Name in black, contact line in blue as most resume templates style links.
+	doc, err := fitz.NewFromMemory(testPDF(
+		"BT /F1 24 Tf 72 720 Td (Jane Doe) Tj ET",
+		"BT /F1 10 Tf 0 0 1 rg 72 690 Td (jane@example.com  555-123-4567) Tj ET",
+		"BT /F1 10 Tf 0 0 0 rg 72 600 Td (Built things at a company) Tj ET",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	r, err := NewRedactor(&utils.AnonymizeConfig{RedactName: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := r.Regions(doc, 0, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]Rect{}
+	for _, reg := range regions {
+		found[reg.Reason] = reg.Rect
+	}
+	for _, reason := range []string{"name", "email", "phone"} {
+		if _, ok := found[reason]; !ok {
+			t.Errorf("no %s region in %+v", reason, regions)
+		}
+	}
+	if len(regions) != 3 {
+		t.Errorf("got %d regions, want 3: %+v", len(regions), regions)
+	}
+	if email, phone := found["email"], found["phone"]; email.X0 > 72 || phone.X1 < 72+30*10*0.55 || email.Y0 != phone.Y0 {
+		t.Errorf("email %+v and phone %+v should cover the contact line", email, phone)
+	}
+	// The name box runs to the widest line so bold or wide glyphs cannot poke out.
+	if name := found["name"]; name.X1 < found["phone"].X1 {
+		t.Errorf("name %+v should extend to the text edge", name)
+	}
+}
+
+func TestRegionsImageOnlyPage(t *testing.T) {
+	// A scanned page: a filled rectangle standing in for the image, and no text.
+	doc, err := fitz.NewFromMemory(testPDF("0 0 0 rg 72 72 468 648 re f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	r, err := NewRedactor(&utils.AnonymizeConfig{RedactName: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := r.Regions(doc, 0, Options{})
+	if !errors.Is(err, ErrNoTextLayer) {
+		t.Fatalf("Regions = %+v, %v; want ErrNoTextLayer", regions, err)
+	}
+}
+
+// testPDF builds a one page US Letter PDF drawing each content stream operator
+// sequence in Helvetica.
+func testPDF(content ...string) []byte {
+	var stream bytes.Buffer
+	for _, c := range content {
+		stream.WriteString(c + "\n")
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", stream.Len(), stream.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
@@ -0,0 +1,35 @@
+package anonymize
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Pattern is a named regular expression whose matches are redacted.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// DefaultPatterns catch the contact details found in the header of most resumes.
+func DefaultPatterns() []Pattern {
+	return []Pattern{
+		{Name: "email", Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+		{Name: "phone", Regexp: regexp.MustCompile(`(?:\+?\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\d{3})[\s.-]?\d{3}[\s.-]?\d{4}`)},
+		{Name: "url", Regexp: regexp.MustCompile(`(?i)(?:https?://|www\.)\S+`)},
+		{Name: "profile", Regexp: regexp.MustCompile(`(?i)\b(?:linkedin\.com|github\.com|gitlab\.com|twitter\.com|x\.com|medium\.com)/\S+`)},
+	}
+}
+
+// CompilePatterns compiles user supplied name -> expression pairs.
+func CompilePatterns(exprs map[string]string) ([]Pattern, error) {
+	patterns := make([]Pattern, 0, len(exprs))
+	for name, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("compile pattern %q: %w", name, err)
+		}
+		patterns = append(patterns, Pattern{Name: name, Regexp: re})
+	}
+	return patterns, nil
+}
@@ -0,0 +1,165 @@
+package anonymize
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"main/utils"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+const (
+	// Extra room around estimated boxes, in points, to cover glyph width estimation error.
+	padX = 4.0
+	padY = 1.5
+	// The name is assumed to be the largest text in the top part of the first page.
+	nameSearchFraction = 0.25
+	nameSizeRatio      = 1.3
+)
+
+// ErrNoTextLayer is returned for a page with no extractable text, such as a scan.
+// Nothing on it can be located, so it must not be published.
+var ErrNoTextLayer = errors.New("page has no text layer to redact; upload a PDF with selectable text")
+
+// Region is an area of a page to black out, in PDF points.
+type Region struct {
+	Reason string
+	Rect   Rect
+}
+
+// Options are the per-resume choices made by the owner.
+type Options struct {
+	HideCompanies bool
+}
+
+// Redactor finds personally identifying text in a PDF so it can be blacked out
+// of the public previews. The source PDF itself is never modified.
+type Redactor struct {
+	patterns   []Pattern
+	redactName bool
+	companies  *regexp.Regexp
+}
+
+func NewRedactor(cfg *utils.AnonymizeConfig) (*Redactor, error) {
+	if cfg == nil {
+		panic("cfg must be non-nil")
+	}
+	extra, err := CompilePatterns(cfg.ExtraPatterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Redactor{
+		patterns:   append(DefaultPatterns(), extra...),
+		redactName: cfg.RedactName,
+		companies:  compileDictionary(cfg.ExtraCompanies),
+	}, nil
+}
+
+// Regions returns every area of the page that should be hidden.
+func (r *Redactor) Regions(doc *fitz.Document, page int, opts Options) ([]Region, error) {
+	lines, err := ExtractLines(doc, page)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrNoTextLayer
+	}
+	bounds, err := doc.Bound(page)
+	if err != nil {
+		return nil, fmt.Errorf("page bounds: %w", err)
+	}
+
+	var regions []Region
+	for _, line := range lines {
+		for _, p := range r.patterns {
+			for _, loc := range p.Regexp.FindAllStringIndex(line.Text, -1) {
+				regions = append(regions, Region{Reason: p.Name, Rect: byteSpan(line, loc[0], loc[1])})
+			}
+		}
+	}
+
+	if opts.HideCompanies {
+		for i, spans := range companySpans(lines, r.companies) {
+			for _, sp := range spans {
+				regions = append(regions, Region{Reason: "company", Rect: byteSpan(lines[i], sp[0], sp[1])})
+			}
+		}
+	}
+
+	if r.redactName && page == 0 {
+		if line, ok := findNameLine(lines, float64(bounds.Dy())); ok {
+			// The name is short and usually set in bold capitals, the case the width
+			// estimate handles worst, so cover the rest of the line up to the text edge.
+			rect := line.Bounds()
+			rect.X1 = max(rect.X1, textEdge(lines, float64(bounds.Dx())))
+			regions = append(regions, Region{Reason: "name", Rect: rect})
+		}
+	}
+
+	return regions, nil
+}
+
+// Apply blacks out regions on a page image rendered at scale pixels per point.
+func Apply(img *image.RGBA, regions []Region, scale float64) {
+	for _, reg := range regions {
+		rect := image.Rect(
+			int((reg.Rect.X0-padX)*scale),
+			int((reg.Rect.Y0-padY)*scale),
+			int((reg.Rect.X1+padX)*scale+0.5),
+			int((reg.Rect.Y1+padY)*scale+0.5),
+		).Intersect(img.Bounds())
+		draw.Draw(img, rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+}
+
+// byteSpan converts a byte range of line.Text (as returned by regexp) to a box.
+func byteSpan(line TextLine, start, end int) Rect {
+	runeStart := utf8.RuneCountInString(line.Text[:start])
+	return line.Span(runeStart, runeStart+utf8.RuneCountInString(line.Text[start:end]))
+}
+
+// textEdge returns the rightmost extent of the text on the page, capped at its width.
+func textEdge(lines []TextLine, pageWidth float64) float64 {
+	var edge float64
+	for _, l := range lines {
+		edge = max(edge, l.Bounds().X1)
+	}
+	return min(edge, pageWidth)
+}
+
+// findNameLine picks the line with the largest font in the top of the page, provided
+// it clearly stands out from the body text.
+func findNameLine(lines []TextLine, pageHeight float64) (TextLine, bool) {
+	if len(lines) == 0 {
+		return TextLine{}, false
+	}
+
+	sizes := make([]float64, 0, len(lines))
+	for _, l := range lines {
+		sizes = append(sizes, l.FontSize)
+	}
+	sort.Float64s(sizes)
+	median := sizes[len(sizes)/2]
+
+	var best TextLine
+	found := false
+	for _, l := range lines {
+		if l.Top > pageHeight*nameSearchFraction {
+			continue
+		}
+		if !found || l.FontSize > best.FontSize {
+			best, found = l, true
+		}
+	}
+	if !found || best.FontSize < median*nameSizeRatio {
+		return TextLine{}, false
+	}
+	return best, true
+}
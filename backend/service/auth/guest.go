@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"main/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Guest session tokens: HS256 JWTs signed with our own secret, never Supabase's,
+// with a fixed issuer and audience so they can't pass as user tokens or vice versa.
+
+const (
+	guestIssuer   = "crackedonpaper"
+	guestAudience = "guest"
+
+	// Cookie and header a guest token is read from.
+	GuestCookieName = "guest_session"
+	GuestHeaderName = "X-Guest-Session"
+)
+
+var ErrInvalidGuestToken = errors.New("invalid guest token")
+
+type GuestTokens struct {
+	secret []byte
+	parser *jwt.Parser
+}
+
+func NewGuestTokens(secret string) *GuestTokens {
+	if secret == "" {
+		panic("secret must be set")
+	}
+	return &GuestTokens{
+		secret: []byte(secret),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithIssuer(guestIssuer),
+			jwt.WithAudience(guestAudience),
+			jwt.WithExpirationRequired(),
+		),
+	}
+}
+
+// Issue signs a token for sessionID that expires at expiresAt.
+func (g *GuestTokens) Issue(sessionID pgtype.UUID, expiresAt time.Time) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    guestIssuer,
+		Audience:  jwt.ClaimStrings{guestAudience},
+		Subject:   sessionID.String(),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	signed, err := token.SignedString(g.secret)
+	if err != nil {
+		return "", fmt.Errorf("sign guest token: %w", err)
+	}
+	return signed, nil
+}
+
+// Verify returns the session ID of a valid, unexpired token.
+func (g *GuestTokens) Verify(raw string) (pgtype.UUID, error) {
+	var claims jwt.RegisteredClaims
+	_, err := g.parser.ParseWithClaims(raw, &claims, func(*jwt.Token) (interface{}, error) {
+		return g.secret, nil
+	})
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("%w: %v", ErrInvalidGuestToken, err)
+	}
+
+	sessionID, err := utils.ConvertStringToUUID(claims.Subject)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("%w: bad subject", ErrInvalidGuestToken)
+	}
+	return sessionID, nil
+}
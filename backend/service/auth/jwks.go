@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Public keys for asymmetric (RS256/ES256) tokens, fetched from the project's JWKS
+// document. Keys are cached and refreshed on an interval; a token signed with a kid
+// we haven't seen triggers an early refetch so key rotation needs no restart.
+
+var ErrUnknownKey = errors.New("no signing key for kid")
+
+// Unknown kids can be attacker-chosen, so refetches they trigger are rate-limited.
+const minRefetchInterval = 30 * time.Second
+
+const fetchTimeout = 10 * time.Second
+
+type JWKS struct {
+	url             string
+	refreshInterval time.Duration
+	client          *http.Client
+	log             *zap.Logger
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+
+	// Serializes fetches so a burst of unknown kids causes one request.
+	fetchMu sync.Mutex
+}
+
+func NewJWKS(url string, refreshInterval time.Duration, log *zap.Logger) *JWKS {
+	if url == "" || log == nil {
+		panic("url and log must be set")
+	}
+	return &JWKS{
+		url:             url,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: fetchTimeout},
+		log:             log,
+		keys:            map[string]crypto.PublicKey{},
+	}
+}
+
+// Run refreshes the key set every refresh interval until ctx is cancelled.
+func (k *JWKS) Run(ctx context.Context) {
+	k.log.Info("JWKS refresher started", zap.String("url", k.url), zap.Duration("interval", k.refreshInterval))
+	defer k.log.Info("JWKS refresher stopped")
+
+	if err := k.Refresh(ctx); err != nil {
+		k.log.Error("Failed to fetch JWKS", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(k.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Refresh(ctx); err != nil {
+				k.log.Error("Failed to refresh JWKS", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Key returns the public key for kid. An unknown kid or a stale cache triggers a
+// refetch; on fetch failure the cached keys keep serving.
+func (k *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	stale := time.Since(k.fetchedAt) > k.refreshInterval
+	canRefetch := time.Since(k.lastAttempt) > minRefetchInterval
+	k.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+	if !canRefetch {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+
+	if err := k.refetch(ctx); err != nil {
+		k.log.Warn("Failed to refetch JWKS", zap.String("kid", kid), zap.Error(err))
+	}
+
+	k.mu.RLock()
+	key, ok = k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// refetch fetches unless another caller already did while this one waited.
+func (k *JWKS) refetch(ctx context.Context) error {
+	k.fetchMu.Lock()
+	defer k.fetchMu.Unlock()
+
+	k.mu.RLock()
+	recent := time.Since(k.lastAttempt) <= minRefetchInterval
+	k.mu.RUnlock()
+	if recent {
+		return nil
+	}
+	return k.fetch(ctx)
+}
+
+// Refresh fetches the key set now, replacing the cache.
+func (k *JWKS) Refresh(ctx context.Context) error {
+	k.fetchMu.Lock()
+	defer k.fetchMu.Unlock()
+	return k.fetch(ctx)
+}
+
+func (k *JWKS) fetch(ctx context.Context) error {
+	k.mu.Lock()
+	k.lastAttempt = time.Now()
+	k.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return fmt.Errorf("build JWKS request: %w", err)
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, j := range doc.Keys {
+		// Encryption keys and unsupported types are skipped, not fatal.
+		if j.Use != "" && j.Use != "sig" {
+			continue
+		}
+		key, err := j.publicKey()
+		if err != nil {
+			k.log.Warn("Skipping JWKS key", zap.String("kid", j.Kid), zap.Error(err))
+			continue
+		}
+		keys[j.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no usable signing keys")
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+
+	k.log.Debug("Fetched JWKS", zap.Int("keys", len(keys)))
+	return nil
+}
+
+// jwk is one entry of a JWKS document (RFC 7517). Only RSA and EC keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		// Rejects points that aren't on the curve.
+		if _, err := key.ECDH(); err != nil {
+			return nil, fmt.Errorf("invalid EC key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -21,18 +21,76 @@ type Claims struct {
 
 type UserIDContextKey struct{}
 
+type GuestSessionIDContextKey struct{}
+
 type AuthService struct {
 	hmacSecret []byte
-	Log        *zap.Logger
+	// Nil when only HS256 tokens are accepted.
+	jwks    *JWKS
+	// Nil when guest voting is disabled.
+	guests  *GuestTokens
+	parser  *jwt.Parser
+	Log     *zap.Logger
 }
 
-func NewAuthService(hmacSecret string, log *zap.Logger) *AuthService {
+// NewAuthService verifies RS256/ES256 tokens against jwks and, when cfg has a JWT
+// secret, HS256 tokens as a fallback. jwks may be nil for HS256-only projects and
+// guests nil when guest voting is off.
+func NewAuthService(cfg *utils.SupabaseConfig, jwks *JWKS, guests *GuestTokens, log *zap.Logger) *AuthService {
+	if cfg == nil || log == nil {
+		panic("cfg and log must be non-nil")
+	}
+
+	var methods []string
+	if jwks != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+	if cfg.JWTSecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithLeeway(cfg.JWTLeeway)}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+	if cfg.JWTRequireExpiry {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+
 	return &AuthService{
-		hmacSecret: []byte(hmacSecret),
+		hmacSecret: []byte(cfg.JWTSecret),
+		jwks:       jwks,
+		guests:     guests,
+		parser:     jwt.NewParser(opts...),
 		Log:        log,
 	}
 }
 
+// keyFunc picks the verification key by algorithm. The parser has already rejected
+// algorithms that aren't enabled.
+func (s *AuthService) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			if s.jwks == nil {
+				return nil, fmt.Errorf("asymmetric tokens are not enabled")
+			}
+			kid, _ := token.Header["kid"].(string)
+			return s.jwks.Key(ctx, kid)
+		case *jwt.SigningMethodHMAC:
+			if len(s.hmacSecret) == 0 {
+				return nil, fmt.Errorf("HS256 tokens are not enabled")
+			}
+			return s.hmacSecret, nil
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	}
+}
+
 func (s *AuthService) ParseJWTToken(c *gin.Context, raw string) error {
 	s.Log.Debug("Parsing JWT token", zap.String("rawToken", raw))
 	
@@ -48,12 +106,7 @@ func (s *AuthService) ParseJWTToken(c *gin.Context, raw string) error {
 		return fmt.Errorf("malformed token: expected 3 segments")
 	}
 
-	t, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.hmacSecret, nil
-	})
+	t, err := s.parser.ParseWithClaims(tokenStr, &Claims{}, s.keyFunc(c.Request.Context()))
 	if err != nil {
 		return fmt.Errorf("error validating token: %w", err)
 	}
@@ -74,8 +127,33 @@ func (s *AuthService) ParseJWTToken(c *gin.Context, raw string) error {
 	return nil
 }
 
+// identified reports whether IdentifyMiddleware already verified the caller's token.
+func identified(c *gin.Context) bool {
+	_, ok := c.Request.Context().Value(UserIDContextKey{}).(string)
+	return ok
+}
+
+// IdentifyMiddleware records the user of a valid bearer token and never rejects the
+// request; missing or bad tokens are left to AuthMiddleware and OptionalAuthMiddleware,
+// which skip verifying again when it succeeded. Used ahead of rate limiting.
+func (s *AuthService) IdentifyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.GetHeader("Authorization"); token != "" {
+			if err := s.ParseJWTToken(c, token); err != nil {
+				s.Log.Debug("Request not identified", zap.Error(err))
+			}
+		}
+		c.Next()
+	}
+}
+
 func (s *AuthService) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if identified(c) {
+			c.Next()
+			return
+		}
+
 		token := c.GetHeader("Authorization")
 		if token == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
@@ -93,7 +171,88 @@ func (s *AuthService) AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+// OptionalAuthMiddleware lets anonymous requests through. A bearer token, if sent,
+// must be valid; otherwise a valid guest token (header or cookie) identifies the
+// guest session. Handlers check GetUserID / GetGuestSessionID themselves.
+func (s *AuthService) OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identified(c) {
+			c.Next()
+			return
+		}
+
+		if token := c.GetHeader("Authorization"); token != "" {
+			if err := s.ParseJWTToken(c, token); err != nil {
+				s.Log.Error("Error parsing token", zap.Error(err))
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if s.guests != nil {
+			if raw := s.guestToken(c); raw != "" {
+				sessionID, err := s.guests.Verify(raw)
+				if err != nil {
+					// A stale guest token shouldn't block the request; the client gets a new session.
+					s.Log.Debug("Ignoring invalid guest token", zap.Error(err))
+				} else {
+					ctx := context.WithValue(c.Request.Context(), GuestSessionIDContextKey{}, sessionID)
+					c.Request = c.Request.WithContext(ctx)
+				}
+			}
+		}
 
+		c.Next()
+	}
+}
+
+func (s *AuthService) guestToken(c *gin.Context) string {
+	if raw := c.GetHeader(GuestHeaderName); raw != "" {
+		return raw
+	}
+	raw, _ := c.Cookie(GuestCookieName)
+	return raw
+}
+
+// GuestTokenFromRequest returns the verified guest session sent with the request,
+// whatever middleware ran. Used when a signed-in user hands over a guest session.
+func (s *AuthService) GuestTokenFromRequest(c *gin.Context) (pgtype.UUID, error) {
+	if s.guests == nil {
+		return pgtype.UUID{}, ErrInvalidGuestToken
+	}
+	return s.guests.Verify(s.guestToken(c))
+}
+
+// GetGuestSessionID returns the guest session set by OptionalAuthMiddleware.
+func (s *AuthService) GetGuestSessionID(c *gin.Context) (pgtype.UUID, bool) {
+	sessionID, ok := c.Request.Context().Value(GuestSessionIDContextKey{}).(pgtype.UUID)
+	return sessionID, ok
+}
+
+// Voter is whoever casts a vote: a signed-in user or, failing that, a guest session.
+// Exactly one of the IDs is valid.
+type Voter struct {
+	UserID         pgtype.UUID
+	GuestSessionID pgtype.UUID
+}
+
+func (v Voter) IsGuest() bool {
+	return !v.UserID.Valid
+}
+
+// GetVoter identifies the caller on routes behind OptionalAuthMiddleware.
+func (s *AuthService) GetVoter(c *gin.Context) (Voter, bool) {
+	if _, ok := c.Request.Context().Value(UserIDContextKey{}).(string); ok {
+		userID, ok := s.GetUserID(c)
+		return Voter{UserID: userID}, ok
+	}
+	if sessionID, ok := s.GetGuestSessionID(c); ok {
+		return Voter{GuestSessionID: sessionID}, true
+	}
+	return Voter{}, false
+}
 
 func (s *AuthService) GetUserID(c *gin.Context) (pgtype.UUID, bool) {
     val := c.Request.Context().Value(UserIDContextKey{})
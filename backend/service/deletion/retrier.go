@@ -0,0 +1,72 @@
+package deletion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sqlc "main/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+const retrierBatchSize = 50
+
+// Retrier finishes purging deleted resumes whose objects could not all be removed
+// when the resume was deleted.
+type Retrier struct {
+	service *DeletionService
+}
+
+func NewRetrier(service *DeletionService) *Retrier {
+	if service == nil {
+		panic("service must be non-nil")
+	}
+	return &Retrier{service: service}
+}
+
+// Run retries due tombstones on every tick until ctx is cancelled.
+func (r *Retrier) Run(ctx context.Context) {
+	log := r.service.log
+	log.Info("Starting deletion retrier", zap.Duration("interval", r.service.cfg.RetryInterval))
+
+	ticker := time.NewTicker(r.service.cfg.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Stopping deletion retrier")
+			return
+		case <-ticker.C:
+			n, err := r.RetryOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Error("Failed to retry resume deletions", zap.Error(err))
+			}
+			if n > 0 {
+				log.Info("Retried resume deletions", zap.Int("tombstones", n))
+			}
+		}
+	}
+}
+
+// RetryOnce claims one batch of due tombstones and purges each, returning how many were attempted.
+func (r *Retrier) RetryOnce(ctx context.Context) (int, error) {
+	s := r.service
+	tombstones, err := s.db.ClaimResumeTombstones(ctx, sqlc.ClaimResumeTombstonesParams{
+		LeaseUntil: pgtype.Timestamptz{Time: time.Now().Add(leaseDuration), Valid: true},
+		BatchSize:  retrierBatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("claim tombstones: %w", err)
+	}
+
+	for i := range tombstones {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		s.purgeOrRetry(ctx, &tombstones[i])
+	}
+	return len(tombstones), nil
+}
@@ -0,0 +1,193 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/spaces"
+	"main/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Deletes resumes. The row goes first, together with a tombstone naming every object
+// it owned; the objects are then removed from the buckets, and the Retrier finishes
+// the job if a bucket call fails.
+
+var (
+	ErrResumeNotFound = errors.New("resume not found")
+	// Deleting the row would cascade to the open match and strand the opponent in_flight.
+	ErrResumeInMatch = errors.New("resume is in an open match")
+)
+
+// How long a claimed tombstone is hidden from other claimers while it is purged.
+const leaseDuration = 5 * time.Minute
+
+type DeletionService struct {
+	pool         *pgxpool.Pool
+	db           *sqlc.Queries
+	resumeBucket *spaces.ResumeBucket
+	webpBucket   *spaces.WebpBucket
+	cfg          *utils.DeletionConfig
+	log          *zap.Logger
+}
+
+func NewDeletionService(pool *pgxpool.Pool, db *sqlc.Queries, resumeBucket *spaces.ResumeBucket, webpBucket *spaces.WebpBucket, cfg *utils.DeletionConfig, log *zap.Logger) *DeletionService {
+	if pool == nil || db == nil || resumeBucket == nil || webpBucket == nil || cfg == nil || log == nil {
+		panic("pool, db, resumeBucket, webpBucket, cfg, and log must be non-nil")
+	}
+	return &DeletionService{pool: pool, db: db, resumeBucket: resumeBucket, webpBucket: webpBucket, cfg: cfg, log: log}
+}
+
+// DeleteResume removes one of the owner's resumes. Storage keys come from the row and
+// its versions, never from the caller. Once the row is gone the delete has succeeded
+// even if the buckets are not yet clean; the tombstone keeps that work tracked.
+// Refused while the resume is in an open match.
+func (s *DeletionService) DeleteResume(ctx context.Context, resumeID, ownerUserID pgtype.UUID) error {
+	tombstone, err := s.tombstone(ctx, resumeID, ownerUserID)
+	if err != nil {
+		return err
+	}
+
+	// The request may be cancelled once the row is gone; the purge should still run.
+	s.purgeOrRetry(context.WithoutCancel(ctx), tombstone)
+	return nil
+}
+
+// tombstone records the resume's objects and deletes its row in one transaction.
+func (s *DeletionService) tombstone(ctx context.Context, resumeID, ownerUserID pgtype.UUID) (*sqlc.AppResumeTombstone, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	resume, err := q.GetResumeByIDForOwnerForUpdate(ctx, sqlc.GetResumeByIDForOwnerForUpdateParams{ID: resumeID, OwnerUserID: ownerUserID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrResumeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock resume: %w", err)
+	}
+	// The lock also keeps matchmaking from pairing it until the delete commits.
+	if resume.InFlight {
+		return nil, ErrResumeInMatch
+	}
+
+	userID, id := resume.OwnerUserID.String(), resume.ID.String()
+	pdfPrefix := s.resumeBucket.ResumePrefix(userID, id)
+
+	keys, err := q.ListResumePdfKeys(ctx, resume.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list pdf keys: %w", err)
+	}
+	legacy := make([]string, 0)
+	for _, key := range keys {
+		if key != "" && !strings.HasPrefix(key, pdfPrefix) {
+			legacy = append(legacy, key)
+		}
+	}
+
+	tombstone, err := q.CreateResumeTombstone(ctx, sqlc.CreateResumeTombstoneParams{
+		ResumeID:    resume.ID,
+		PdfKeys:     legacy,
+		PdfPrefix:   pdfPrefix,
+		ImagePrefix: s.webpBucket.Prefix(id, ""),
+		// Older versions can still point here even after the current one was re-rendered.
+		LegacyImagePrefix: s.webpBucket.LegacyPrefix(userID, id),
+		// Leased to this request so the retrier doesn't purge it concurrently.
+		RunAt: pgtype.Timestamptz{Time: time.Now().Add(leaseDuration), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create tombstone: %w", err)
+	}
+
+	if err := q.DeleteResumeByIDForOwner(ctx, sqlc.DeleteResumeByIDForOwnerParams{ID: resume.ID, OwnerUserID: ownerUserID}); err != nil {
+		return nil, fmt.Errorf("delete resume: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.log.Info("Deleted resume",
+		zap.String("resume_id", id),
+		zap.String("tombstone_id", tombstone.ID.String()),
+	)
+	return &tombstone, nil
+}
+
+// purgeOrRetry removes a tombstone's objects, clearing it on success and scheduling a
+// retry with exponential backoff on failure.
+func (s *DeletionService) purgeOrRetry(ctx context.Context, t *sqlc.AppResumeTombstone) {
+	err := s.purge(ctx, t)
+	if err == nil {
+		if err := s.db.DeleteResumeTombstone(ctx, t.ID); err != nil {
+			s.log.Error("Failed to clear resume tombstone", zap.String("tombstone_id", t.ID.String()), zap.Error(err))
+		}
+		return
+	}
+
+	delay := s.cfg.RetryBaseDelay << min(t.Attempts, 20)
+	if delay <= 0 || delay > s.cfg.RetryMaxDelay {
+		delay = s.cfg.RetryMaxDelay
+	}
+	s.log.Warn("Failed to purge deleted resume objects, will retry",
+		zap.String("tombstone_id", t.ID.String()),
+		zap.String("resume_id", t.ResumeID.String()),
+		zap.Int32("attempt", t.Attempts+1),
+		zap.Duration("retry_in", delay),
+		zap.Error(err),
+	)
+
+	if err := s.db.RetryResumeTombstone(ctx, sqlc.RetryResumeTombstoneParams{
+		RunAt:     pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+		LastError: pgtype.Text{String: err.Error(), Valid: true},
+		ID:        t.ID,
+	}); err != nil {
+		s.log.Error("Failed to reschedule resume tombstone", zap.String("tombstone_id", t.ID.String()), zap.Error(err))
+	}
+}
+
+// purge deletes every object a tombstone names. Deleting a missing object succeeds,
+// so a partially purged tombstone can simply be purged again.
+func (s *DeletionService) purge(ctx context.Context, t *sqlc.AppResumeTombstone) error {
+	if err := s.webpBucket.DeletePrefix(ctx, t.ImagePrefix); err != nil {
+		return fmt.Errorf("delete renditions: %w", err)
+	}
+	if t.LegacyImagePrefix != "" {
+		if err := s.webpBucket.DeletePrefix(ctx, t.LegacyImagePrefix); err != nil {
+			return fmt.Errorf("delete legacy renditions: %w", err)
+		}
+	}
+	if err := s.resumeBucket.DeletePrefix(ctx, t.PdfPrefix); err != nil {
+		return fmt.Errorf("delete pdfs: %w", err)
+	}
+
+	// Name-based keys could be shared by another resume with the same name.
+	var legacy []string
+	for _, key := range t.PdfKeys {
+		refs, err := s.db.CountPdfStorageKeyReferences(ctx, pgtype.Text{String: key, Valid: true})
+		if err != nil {
+			return fmt.Errorf("count pdf references: %w", err)
+		}
+		if refs == 0 {
+			legacy = append(legacy, key)
+		}
+	}
+	if len(legacy) > 0 {
+		if err := s.resumeBucket.DeleteObjects(ctx, legacy); err != nil {
+			return fmt.Errorf("delete legacy pdfs: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package feedback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlc "main/db/sqlc"
+	"main/service/match"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Who can read a piece of feedback. Mirrors the feedback_visibility_valid check constraint.
+const (
+	VisibilityOwner  = "owner"
+	VisibilityPublic = "public"
+)
+
+// Raised by feedback_author_target_unique when a voter leaves feedback twice.
+const uniqueViolation = "23505"
+
+var (
+	ErrMatchNotFound     = errors.New("match not found")
+	ErrResumeNotFound    = errors.New("resume not found")
+	ErrNotVoter          = errors.New("only the voter of a resolved match can leave feedback")
+	ErrTargetNotInMatch  = errors.New("resume is not part of this match")
+	ErrEmptyFeedback     = errors.New("feedback must have text or tags")
+	ErrDuplicateFeedback = errors.New("feedback already submitted for this resume")
+)
+
+type OwnerFeedback struct {
+	Feedback  []sqlc.AppFeedback
+	TagCounts []sqlc.CountFeedbackTagsForResumeRow
+}
+
+type FeedbackService struct {
+	db *sqlc.Queries
+}
+
+func NewFeedbackService(db *sqlc.Queries) *FeedbackService {
+	if db == nil {
+		panic("db must be non-nil")
+	}
+	return &FeedbackService{db: db}
+}
+
+// Submit records feedback on one resume of a match. Only the user who voted on the
+// match may leave feedback, and only once per resume.
+func (s *FeedbackService) Submit(ctx context.Context, matchID, targetResumeID, authorUserID pgtype.UUID, text string, tags []string, visibility string) (*sqlc.AppFeedback, error) {
+	text = strings.TrimSpace(text)
+	tags = normalizeTags(tags)
+	if text == "" && len(tags) == 0 {
+		return nil, ErrEmptyFeedback
+	}
+
+	m, err := s.db.GetMatchByID(ctx, matchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get match: %w", err)
+	}
+	if m.State != match.StateResolved || m.DecidedByUserID != authorUserID {
+		return nil, ErrNotVoter
+	}
+	if targetResumeID != m.ResumeAID && targetResumeID != m.ResumeBID {
+		return nil, ErrTargetNotInMatch
+	}
+
+	fb, err := s.db.CreateFeedback(ctx, sqlc.CreateFeedbackParams{
+		MatchID:        matchID,
+		TargetResumeID: targetResumeID,
+		AuthorUserID:   authorUserID,
+		Visibility:     visibility,
+		Text:           text,
+		Tags:           tags,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, ErrDuplicateFeedback
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create feedback: %w", err)
+	}
+
+	return &fb, nil
+}
+
+// ListForOwner returns every piece of feedback left on a resume plus per-tag counts.
+// Resumes the caller does not own are reported as not found.
+func (s *FeedbackService) ListForOwner(ctx context.Context, resumeID, ownerUserID pgtype.UUID) (*OwnerFeedback, error) {
+	_, err := s.db.GetResumeByIDForOwner(ctx, sqlc.GetResumeByIDForOwnerParams{
+		ID:          resumeID,
+		OwnerUserID: ownerUserID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrResumeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get resume: %w", err)
+	}
+
+	feedback, err := s.db.ListFeedbackForResume(ctx, resumeID)
+	if err != nil {
+		return nil, fmt.Errorf("list feedback: %w", err)
+	}
+
+	tagCounts, err := s.db.CountFeedbackTagsForResume(ctx, resumeID)
+	if err != nil {
+		return nil, fmt.Errorf("count feedback tags: %w", err)
+	}
+
+	return &OwnerFeedback{Feedback: feedback, TagCounts: tagCounts}, nil
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags so counts group correctly.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	return out
+}
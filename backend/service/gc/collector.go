@@ -0,0 +1,222 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/spaces"
+
+	"go.uber.org/zap"
+)
+
+// Reconciles the buckets with the database: any object that no resume, resume
+// version or live upload intent points at, and that is older than the grace period,
+// is deleted.
+
+// Only objects under these prefixes are considered; anything else in a bucket was not
+// written by the backend and is left alone.
+const (
+	resumeLayout = "/resumes/"
+	uploadLayout = "/uploads/"
+	webpLayout   = "resumes/"
+	// Renditions written before public keys stopped carrying the owner's user ID.
+	legacyWebpLayout = "users/"
+)
+
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+type BucketReport struct {
+	Bucket     string `json:"bucket"`
+	Scanned    int    `json:"scanned"`
+	Referenced int    `json:"referenced"`
+	// Unreferenced but younger than the grace period.
+	InGrace       int      `json:"in_grace"`
+	Skipped       int      `json:"skipped"`
+	Orphaned      []Object `json:"orphaned"`
+	OrphanedBytes int64    `json:"orphaned_bytes"`
+	Deleted       int      `json:"deleted"`
+}
+
+type Report struct {
+	DryRun      bool         `json:"dry_run"`
+	GracePeriod string       `json:"grace_period"`
+	StartedAt   time.Time    `json:"started_at"`
+	Resume      BucketReport `json:"resume_bucket"`
+	Webp        BucketReport `json:"webp_bucket"`
+}
+
+type Collector struct {
+	db           *sqlc.Queries
+	resumeBucket *spaces.ResumeBucket
+	webpBucket   *spaces.WebpBucket
+	grace        time.Duration
+	log          *zap.Logger
+}
+
+func NewCollector(db *sqlc.Queries, resumeBucket *spaces.ResumeBucket, webpBucket *spaces.WebpBucket, grace time.Duration, log *zap.Logger) *Collector {
+	if db == nil || resumeBucket == nil || webpBucket == nil || log == nil {
+		panic("db, resumeBucket, webpBucket, and log must be non-nil")
+	}
+	return &Collector{db: db, resumeBucket: resumeBucket, webpBucket: webpBucket, grace: grace, log: log}
+}
+
+// Run collects on every tick until ctx is cancelled.
+func (c *Collector) Run(ctx context.Context, interval time.Duration, dryRun bool) {
+	c.log.Info("Starting orphan collector",
+		zap.Duration("interval", interval),
+		zap.Duration("grace_period", c.grace),
+		zap.Bool("dry_run", dryRun),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Info("Stopping orphan collector")
+			return
+		case <-ticker.C:
+			if _, err := c.Collect(ctx, dryRun); err != nil && ctx.Err() == nil {
+				c.log.Error("Failed to collect orphaned objects", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Collect lists both buckets, diffs them against the database and deletes the orphans
+// past the grace period, or only reports them when dryRun is set.
+func (c *Collector) Collect(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun, GracePeriod: c.grace.String(), StartedAt: time.Now()}
+	cutoff := report.StartedAt.Add(-c.grace)
+
+	// Buckets are listed before the references are read: an object uploaded after the
+	// listing can't be judged, and one listed before its row commits is inside the grace period.
+	resumeObjs, err := c.resumeBucket.ListObjects(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list resume bucket: %w", err)
+	}
+	webpObjs, err := c.webpBucket.ListObjects(ctx, webpLayout)
+	if err != nil {
+		return nil, fmt.Errorf("list webp bucket: %w", err)
+	}
+	legacyWebpObjs, err := c.webpBucket.ListObjects(ctx, legacyWebpLayout)
+	if err != nil {
+		return nil, fmt.Errorf("list webp bucket: %w", err)
+	}
+	webpObjs = append(webpObjs, legacyWebpObjs...)
+
+	pdfKeys, err := c.db.ListPdfStorageKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list pdf keys: %w", err)
+	}
+	pdfRefs := make(map[string]struct{}, len(pdfKeys))
+	for _, row := range pdfKeys {
+		pdfRefs[row.PdfStorageKey.String] = struct{}{}
+	}
+
+	// Staged uploads are kept while their intent can still be finalized. After that,
+	// including files posted again with a form that outlived its intent, they go.
+	uploadKeys, err := c.db.ListUploadIntentKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list upload keys: %w", err)
+	}
+	for _, key := range uploadKeys {
+		pdfRefs[key] = struct{}{}
+	}
+
+	prefixes, err := c.db.ListImageKeyPrefixes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list image prefixes: %w", err)
+	}
+	imageRefs := make(map[string]struct{}, len(prefixes))
+	for _, p := range prefixes {
+		imageRefs[p] = struct{}{}
+	}
+
+	report.Resume = c.diff(c.resumeBucket.Name(), resumeObjs, cutoff,
+		func(key string) bool {
+			return strings.Contains(key, resumeLayout) || strings.Contains(key, uploadLayout)
+		},
+		func(key string) bool { _, ok := pdfRefs[key]; return ok },
+	)
+	report.Webp = c.diff(c.webpBucket.Name(), webpObjs, cutoff,
+		func(key string) bool {
+			return strings.HasPrefix(key, webpLayout) || strings.HasPrefix(key, legacyWebpLayout)
+		},
+		func(key string) bool { return underPrefix(key, imageRefs) },
+	)
+
+	if !dryRun {
+		if err := deleteOrphans(ctx, c.resumeBucket, &report.Resume); err != nil {
+			return report, fmt.Errorf("delete resume orphans: %w", err)
+		}
+		if err := deleteOrphans(ctx, c.webpBucket, &report.Webp); err != nil {
+			return report, fmt.Errorf("delete webp orphans: %w", err)
+		}
+	}
+
+	c.log.Info("Collected orphaned objects",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("resume_scanned", report.Resume.Scanned),
+		zap.Int("resume_orphaned", len(report.Resume.Orphaned)),
+		zap.Int("resume_deleted", report.Resume.Deleted),
+		zap.Int("webp_scanned", report.Webp.Scanned),
+		zap.Int("webp_orphaned", len(report.Webp.Orphaned)),
+		zap.Int("webp_deleted", report.Webp.Deleted),
+	)
+	return report, nil
+}
+
+func (c *Collector) diff(bucket string, objs []spaces.Object, cutoff time.Time, managed, referenced func(string) bool) BucketReport {
+	r := BucketReport{Bucket: bucket, Orphaned: []Object{}}
+	for _, o := range objs {
+		r.Scanned++
+		key := o.Key
+		switch {
+		case !managed(key):
+			r.Skipped++
+		case referenced(key):
+			r.Referenced++
+		case o.LastModified.IsZero() || o.LastModified.After(cutoff):
+			r.InGrace++
+		default:
+			obj := Object{Key: key, Size: o.Size, LastModified: o.LastModified}
+			r.Orphaned = append(r.Orphaned, obj)
+			r.OrphanedBytes += obj.Size
+		}
+	}
+	return r
+}
+
+// underPrefix reports whether key is, or sits under, one of the referenced prefixes.
+func underPrefix(key string, prefixes map[string]struct{}) bool {
+	if _, ok := prefixes[key]; ok {
+		return true
+	}
+	for i := strings.LastIndex(key, "/"); i > 0; i = strings.LastIndex(key[:i], "/") {
+		if _, ok := prefixes[key[:i+1]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteOrphans(ctx context.Context, store spaces.ObjectStore, r *BucketReport) error {
+	keys := make([]string, len(r.Orphaned))
+	for i, o := range r.Orphaned {
+		keys[i] = o.Key
+	}
+	if err := store.DeleteObjects(ctx, keys); err != nil {
+		return err
+	}
+	r.Deleted = len(keys)
+	return nil
+}
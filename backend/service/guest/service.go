@@ -0,0 +1,121 @@
+package guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/auth"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Guest voting sessions: issued to anonymous visitors so they can vote, and merged
+// into the visitor's account once they sign in.
+
+var (
+	ErrSessionNotFound = errors.New("guest session not found")
+	ErrAlreadyMerged   = errors.New("guest session was merged into another account")
+)
+
+type Session struct {
+	ID        pgtype.UUID
+	Token     string
+	ExpiresAt time.Time
+}
+
+type GuestService struct {
+	pool   *pgxpool.Pool
+	db     *sqlc.Queries
+	tokens *auth.GuestTokens
+	ttl    time.Duration
+	log    *zap.Logger
+}
+
+func NewGuestService(pool *pgxpool.Pool, db *sqlc.Queries, tokens *auth.GuestTokens, ttl time.Duration, log *zap.Logger) *GuestService {
+	if pool == nil || db == nil || tokens == nil || log == nil {
+		panic("pool, db, tokens, and log must be non-nil")
+	}
+	return &GuestService{pool: pool, db: db, tokens: tokens, ttl: ttl, log: log}
+}
+
+// CreateSession records a new guest session and signs a token for it.
+func (s *GuestService) CreateSession(ctx context.Context) (*Session, error) {
+	expiresAt := time.Now().Add(s.ttl)
+	session, err := s.db.CreateGuestSession(ctx, pgtype.Timestamptz{Time: expiresAt, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("create guest session: %w", err)
+	}
+
+	token, err := s.tokens.Issue(session.ID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{ID: session.ID, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// Merge hands a guest session's votes to userID and closes the session. Votes the
+// guest cast on userID's own resumes are recorded as violations and their rating
+// changes reversed instead of being handed over. Merging into the same account again
+// is a no-op.
+func (s *GuestService) Merge(ctx context.Context, sessionID, userID pgtype.UUID) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	_, err = q.MarkGuestSessionMerged(ctx, sqlc.MarkGuestSessionMergedParams{UserID: userID, ID: sessionID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Missing, or merged already.
+		session, err := q.GetGuestSession(ctx, sessionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrSessionNotFound
+		}
+		if err != nil {
+			return 0, fmt.Errorf("get guest session: %w", err)
+		}
+		if session.MergedIntoUserID != userID {
+			return 0, ErrAlreadyMerged
+		}
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("mark guest session merged: %w", err)
+	}
+
+	selfVotes, err := q.RecordGuestSelfVotes(ctx, sqlc.RecordGuestSelfVotesParams{UserID: userID, GuestSessionID: sessionID})
+	if err != nil {
+		return 0, fmt.Errorf("record guest self votes: %w", err)
+	}
+	reversed, err := q.ReverseGuestSelfVotes(ctx, sqlc.ReverseGuestSelfVotesParams{GuestSessionID: sessionID, UserID: userID})
+	if err != nil {
+		return 0, fmt.Errorf("reverse guest self votes: %w", err)
+	}
+
+	votes, err := q.AttributeGuestVotesToUser(ctx, sqlc.AttributeGuestVotesToUserParams{UserID: userID, GuestSessionID: sessionID})
+	if err != nil {
+		return 0, fmt.Errorf("attribute guest votes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.log.Info("Merged guest session",
+		zap.String("guest_session_id", sessionID.String()),
+		zap.String("user_id", userID.String()),
+		zap.Int64("votes", votes),
+		zap.Int64("self_votes", selfVotes),
+		zap.Int64("reversed", reversed),
+	)
+	return votes, nil
+}
@@ -0,0 +1,54 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version of the manifest layout, bumped on incompatible changes.
+const manifestVersion = 1
+
+// Rendition is one encoded size of a page. Key is the full object key in the webp bucket.
+type Rendition struct {
+	Key    string `json:"key"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Filled in for API responses; never stored.
+	URL string `json:"url,omitempty"`
+}
+
+// PageRenditions lists every size of a page, narrowest first, ready for a srcset.
+type PageRenditions struct {
+	Page       int         `json:"page"`
+	Renditions []Rendition `json:"renditions"`
+}
+
+// Manifest describes a rendition set. It is uploaded as manifest.json next to the
+// renditions and stored on the resume row so the API doesn't have to fetch it.
+type Manifest struct {
+	Version int              `json:"version"`
+	Pages   []PageRenditions `json:"pages"`
+}
+
+// ParseManifest decodes a stored manifest. Resumes without one yield an empty page list.
+func ParseManifest(raw json.RawMessage) ([]PageRenditions, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return []PageRenditions{}, nil
+	}
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("decode image manifest: %w", err)
+	}
+	return m.Pages, nil
+}
+
+// WithURLs sets every rendition's URL from its key, in place, and returns pages.
+func WithURLs(pages []PageRenditions, url func(key string) string) []PageRenditions {
+	for i := range pages {
+		for j := range pages[i].Renditions {
+			r := &pages[i].Renditions[j]
+			r.URL = url(r.Key)
+		}
+	}
+	return pages
+}
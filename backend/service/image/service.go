@@ -3,11 +3,11 @@ package image
 import (
 	"bytes"
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
-	"io"
+	"image"
+	"main/service/anonymize"
 	"main/service/spaces"
-	"mime/multipart"
 
 	"github.com/chai2010/webp"
 	"github.com/disintegration/imaging"
@@ -21,90 +21,146 @@ type ImageMetadata struct {
 	ImageKeyPrefix pgtype.Text
 }
 
+// Stages reported through the onStage callback of RenderRenditions.
+const (
+	StageRendering = "rendering"
+	StageRedacting = "redacting"
+)
+
+const webpQuality = 90
+
 type ImageService struct {
 	log *zap.Logger
 	webpBucket *spaces.WebpBucket
+	redactor *anonymize.Redactor
+	// Rendition widths in pixels, ascending.
+	widths []int
 }
 
-func NewImageService(log *zap.Logger, webpBucket *spaces.WebpBucket) *ImageService {
-	if log == nil || webpBucket == nil {
-		panic("log and webpBucket must be non-nil")
+func NewImageService(log *zap.Logger, webpBucket *spaces.WebpBucket, redactor *anonymize.Redactor, widths []int) *ImageService {
+	if log == nil || webpBucket == nil || redactor == nil || len(widths) == 0 {
+		panic("log, webpBucket, and redactor must be non-nil and widths non-empty")
 	}
-	return &ImageService{log: log, webpBucket: webpBucket}
+	return &ImageService{log: log, webpBucket: webpBucket, redactor: redactor, widths: widths}
 }
 
-
-// Convert a PDF to a webp image of varying sizes.
-func (s *ImageService) ConvertPDFToWebp(ctx context.Context, userID, resumeID string, file *multipart.FileHeader) (string, error) {
-	if file == nil {
-		s.log.Error("File is nil")
-		return "", errors.New("file is nil")
-	}
-
-	// Open the uploaded file 
-	src, err := file.Open()
-	if err != nil {
-		s.log.Error("Failed to open file", zap.Error(err))
-		return "", fmt.Errorf("failed to open file: %w", err)
+// RenderRenditions renders every page of a PDF, redacts it and uploads one webp per
+// configured width plus a manifest.json under {resume prefix}/{version}/.
+// Returns that prefix and the manifest. onStage, if non-nil, is called as each stage starts.
+func (s *ImageService) RenderRenditions(ctx context.Context, resumeID, version string, pdfBytes []byte, opts anonymize.Options, onStage func(stage string)) (string, *Manifest, error) {
+	if onStage == nil {
+		onStage = func(string) {}
 	}
-	defer src.Close()
 
-	// Read file content
-	fileBytes := make([]byte, file.Size)
-	_, err = io.ReadFull(src, fileBytes)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
-	}
+	onStage(StageRendering)
 
 	// Create a new document from the PDF bytes using go-fitz
-	doc, err := fitz.NewFromMemory(fileBytes)
+	doc, err := fitz.NewFromMemory(pdfBytes)
 	if err != nil {
 		s.log.Error("Failed to create PDF document", zap.Error(err))
-		return "", fmt.Errorf("failed to parse PDF: %w", err)
+		return "", nil, fmt.Errorf("failed to parse PDF: %w", err)
 	}
 	defer doc.Close()
 
-	// Get first page
-	img, err := doc.Image(0)
-	if err != nil {
-		s.log.Error("Failed to render PDF page", zap.Error(err))
-		return "", fmt.Errorf("failed to render PDF page: %w", err)
+	// Pages are rendered at 300 DPI, wide enough for every configured width.
+	pages := make([]*image.RGBA, doc.NumPage())
+	for i := range pages {
+		pages[i], err = doc.Image(i)
+		if err != nil {
+			s.log.Error("Failed to render PDF page", zap.Int("page", i), zap.Error(err))
+			return "", nil, fmt.Errorf("failed to render PDF page %d: %w", i+1, err)
+		}
 	}
 
-	const (
-		defaultImageWidth = 800
-		webpQuality = 90
-	)
-
-	// Resize to standard resume preview size (e.g., 800px width, maintain aspect ratio)
-	resized := imaging.Resize(img, defaultImageWidth, 0, imaging.Lanczos)
+	onStage(StageRedacting)
 
-	// Convert to WebP format
-	var webpBuffer bytes.Buffer
-	err = webp.Encode(&webpBuffer, resized, &webp.Options{
-		Lossless: false,
-		Quality: float32(webpQuality),
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to encode as WebP: %w", err)
+	// Black out contact details and the name before anything leaves this function.
+	// The webps are public; the original PDF stays private in the resume bucket.
+	for i, img := range pages {
+		if err := s.redact(doc, i, img, opts); err != nil {
+			s.log.Error("Failed to redact PDF page", zap.Int("page", i), zap.Error(err))
+			return "", nil, fmt.Errorf("failed to redact PDF page %d: %w", i+1, err)
+		}
 	}
 
-	filename := file.Filename
+	manifest := &Manifest{Version: manifestVersion}
+	var totalBytes int
+	for i, img := range pages {
+		page := PageRenditions{Page: i + 1}
+		for _, width := range s.widths {
+			// Never upscale; a narrow page just gets its native width.
+			var resized image.Image = img
+			if width < img.Bounds().Dx() {
+				resized = imaging.Resize(img, width, 0, imaging.Lanczos)
+			}
+			// Every width past the native one collapses onto it; upload that only once.
+			if n := len(page.Renditions); n > 0 && page.Renditions[n-1].Width == resized.Bounds().Dx() {
+				break
+			}
+
+			var webpBuffer bytes.Buffer
+			err = webp.Encode(&webpBuffer, resized, &webp.Options{
+				Lossless: false,
+				Quality: float32(webpQuality),
+			})
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to encode as WebP: %w", err)
+			}
+
+			objectName := fmt.Sprintf("%s/page-%d-%dw.webp", version, page.Page, resized.Bounds().Dx())
+			err = s.webpBucket.UploadBytes(ctx, resumeID, objectName, webpBuffer.Bytes(), "image/webp")
+			if err != nil {
+				s.log.Error("Failed to upload WebP to bucket", zap.Error(err))
+				return "", nil, fmt.Errorf("failed to upload WebP: %w", err)
+			}
+			totalBytes += webpBuffer.Len()
+
+			page.Renditions = append(page.Renditions, Rendition{
+				Key:    s.webpBucket.Prefix(resumeID, objectName),
+				Width:  resized.Bounds().Dx(),
+				Height: resized.Bounds().Dy(),
+			})
+		}
+		manifest.Pages = append(manifest.Pages, page)
+	}
 
-	err = s.webpBucket.UploadBytes(ctx, userID, resumeID, filename, webpBuffer.Bytes(), "image/webp")
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	err = s.webpBucket.UploadBytes(ctx, resumeID, version+"/manifest.json", manifestJSON, "application/json")
 	if err != nil {
-		s.log.Error("Failed to upload WebP to bucket", zap.Error(err))
-		return "", fmt.Errorf("failed to upload WebP: %w", err)
+		s.log.Error("Failed to upload manifest to bucket", zap.Error(err))
+		return "", nil, fmt.Errorf("failed to upload manifest: %w", err)
 	}
 
-	s.log.Info("Successfully converted PDF to WebP", 
+	s.log.Info("Successfully converted PDF to WebP",
 		zap.String("resume_id", resumeID),
-		zap.String("filename", filename),
-		zap.Int("webp_size_bytes", webpBuffer.Len()),
+		zap.String("version", version),
+		zap.Int("pages", len(pages)),
+		zap.Int("widths", len(s.widths)),
+		zap.Int("webp_size_bytes", totalBytes),
 	)
 
-	webpKey := s.webpBucket.Prefix(userID, resumeID, filename)
+	return s.webpBucket.Prefix(resumeID, version+"/"), manifest, nil
+}
+
+// redact blacks out PII on a rendered page. Fails closed: if the text layer can't be
+// read or is empty (a scanned page) the preview is not published.
+func (s *ImageService) redact(doc *fitz.Document, page int, img *image.RGBA, opts anonymize.Options) error {
+	regions, err := s.redactor.Regions(doc, page, opts)
+	if err != nil {
+		return err
+	}
+
+	bounds, err := doc.Bound(page)
+	if err != nil {
+		return fmt.Errorf("page bounds: %w", err)
+	}
+	scale := float64(img.Bounds().Dx()) / float64(bounds.Dx())
 
-	return webpKey, nil 
+	anonymize.Apply(img, regions, scale)
 
+	s.log.Debug("Redacted PDF page", zap.Int("page", page), zap.Int("regions", len(regions)))
+	return nil
 }
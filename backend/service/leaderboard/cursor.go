@@ -0,0 +1,39 @@
+package leaderboard
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor marks the last row of a page. Rank is carried along so later pages
+// can number their rows without counting everything above them.
+type cursor struct {
+	Elo  int32       `json:"e"`
+	ID   pgtype.UUID `json:"i"`
+	Rank int         `json:"r"`
+}
+
+func (c cursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || !c.ID.Valid || c.Rank < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
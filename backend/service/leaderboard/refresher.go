@@ -0,0 +1,139 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/spaces"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+// Refresher keeps app.leaderboard up to date. It refreshes on a fixed interval and
+// early once enough matches have been resolved since the last refresh. After each
+// refresh it deletes the renditions that re-renders replaced before it started.
+type Refresher struct {
+	db           *sqlc.Queries
+	webpBucket   *spaces.WebpBucket
+	interval     time.Duration
+	afterMatches int32
+	resolved     atomic.Int32
+	trigger      chan struct{}
+	log          *zap.Logger
+}
+
+// NewRefresher creates a refresher. afterMatches of 0 disables match-count triggers.
+func NewRefresher(db *sqlc.Queries, webpBucket *spaces.WebpBucket, interval time.Duration, afterMatches int32, log *zap.Logger) *Refresher {
+	if db == nil || webpBucket == nil || log == nil {
+		panic("db, webpBucket, and log must be non-nil")
+	}
+	return &Refresher{
+		db:           db,
+		webpBucket:   webpBucket,
+		interval:     interval,
+		afterMatches: afterMatches,
+		trigger:      make(chan struct{}, 1),
+		log:          log,
+	}
+}
+
+// MatchResolved counts a resolved match and schedules a refresh once the threshold is hit.
+func (r *Refresher) MatchResolved() {
+	if r.afterMatches <= 0 || r.resolved.Add(1) < r.afterMatches {
+		return
+	}
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes until ctx is cancelled.
+func (r *Refresher) Run(ctx context.Context) {
+	r.log.Info("Starting leaderboard refresher",
+		zap.Duration("interval", r.interval),
+		zap.Int32("after_matches", r.afterMatches),
+	)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info("Stopping leaderboard refresher")
+			return
+		case <-ticker.C:
+		case <-r.trigger:
+		}
+
+		if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("Failed to refresh leaderboard", zap.Error(err))
+		}
+	}
+}
+
+// Refresh rebuilds the materialized view. The first refresh has to be a plain one
+// because CONCURRENTLY cannot run against a view that was created WITH NO DATA.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	r.resolved.Store(0)
+	start := time.Now()
+
+	populated, err := r.db.IsLeaderboardPopulated(ctx)
+	if err != nil {
+		return fmt.Errorf("check leaderboard populated: %w", err)
+	}
+
+	if populated.Bool {
+		err = r.db.RefreshLeaderboardConcurrently(ctx)
+	} else {
+		err = r.db.RefreshLeaderboard(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("refresh leaderboard: %w", err)
+	}
+
+	r.log.Info("Refreshed leaderboard",
+		zap.Bool("concurrent", populated.Bool),
+		zap.Duration("took", time.Since(start)),
+	)
+
+	r.deleteSuperseded(ctx, start)
+	return nil
+}
+
+// deleteSuperseded removes renditions replaced before the refresh that started at
+// refreshedFrom; the view no longer lists them. One that fails to delete is deferred
+// to the next refresh.
+func (r *Refresher) deleteSuperseded(ctx context.Context, refreshedFrom time.Time) {
+	prefixes, err := r.db.ClaimSupersededRenditions(ctx, pgtype.Timestamptz{Time: refreshedFrom, Valid: true})
+	if err != nil {
+		r.log.Warn("Failed to claim superseded renditions", zap.Error(err))
+		return
+	}
+
+	for _, prefix := range prefixes {
+		if err := r.deleteUnreferenced(ctx, prefix); err != nil {
+			r.log.Warn("Failed to delete superseded renditions", zap.String("prefix", prefix), zap.Error(err))
+			if err := r.db.DeferRenditionDeletion(ctx, prefix); err != nil {
+				r.log.Error("Failed to defer superseded renditions", zap.String("prefix", prefix), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (r *Refresher) deleteUnreferenced(ctx context.Context, prefix string) error {
+	// A rollback may have pointed a resume back at it since.
+	refs, err := r.db.CountImageKeyPrefixReferences(ctx, pgtype.Text{String: prefix, Valid: true})
+	if err != nil {
+		return fmt.Errorf("count references: %w", err)
+	}
+	if refs > 0 {
+		return nil
+	}
+	return r.webpBucket.DeletePrefix(ctx, prefix)
+}
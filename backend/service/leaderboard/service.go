@@ -0,0 +1,116 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+
+	sqlc "main/db/sqlc"
+	"main/service/image"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Entry struct {
+	Rank          int
+	ResumeID      pgtype.UUID
+	CurrentEloInt int32
+	BattlesCount  int32
+	Pages         []image.PageRenditions
+}
+
+type Page struct {
+	Entries []Entry
+	// Empty when there are no more rows.
+	NextCursor string
+}
+
+// Where leaderboard pages are read from.
+const (
+	SourceLive         = "live"
+	SourceMaterialized = "materialized"
+)
+
+type LeaderboardService struct {
+	db     *sqlc.Queries
+	source string
+}
+
+func NewLeaderboardService(db *sqlc.Queries, source string) *LeaderboardService {
+	if db == nil {
+		panic("db must be non-nil")
+	}
+	if source != SourceLive && source != SourceMaterialized {
+		panic("source must be live or materialized")
+	}
+	return &LeaderboardService{db: db, source: source}
+}
+
+// List returns one page of the (industry, yoe_bucket) leaderboard, highest Elo first.
+func (s *LeaderboardService) List(ctx context.Context, industry, yoeBucket string, limit int32, after string) (*Page, error) {
+	c, err := decodeCursor(after)
+	if err != nil {
+		return nil, err
+	}
+
+	params := sqlc.ListLeaderboardParams{
+		Industry:  industry,
+		YoeBucket: yoeBucket,
+		// Fetch one extra row to learn whether another page exists.
+		RowLimit: limit + 1,
+	}
+	rank := 0
+	if c != nil {
+		params.CursorElo = pgtype.Int4{Int32: c.Elo, Valid: true}
+		params.CursorID = c.ID
+		rank = c.Rank
+	}
+
+	rows, err := s.listRows(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("list leaderboard: %w", err)
+	}
+
+	page := &Page{Entries: make([]Entry, 0, min(len(rows), int(limit)))}
+	for i, row := range rows {
+		if i == int(limit) {
+			last := page.Entries[len(page.Entries)-1]
+			page.NextCursor = cursor{Elo: last.CurrentEloInt, ID: last.ResumeID, Rank: last.Rank}.encode()
+			break
+		}
+		pages, err := image.ParseManifest(row.ImageManifest)
+		if err != nil {
+			return nil, err
+		}
+		page.Entries = append(page.Entries, Entry{
+			Rank:          rank + i + 1,
+			ResumeID:      row.ID,
+			CurrentEloInt: row.CurrentEloInt,
+			BattlesCount:  row.BattlesCount,
+			Pages:         pages,
+		})
+	}
+
+	return page, nil
+}
+
+func (s *LeaderboardService) listRows(ctx context.Context, params sqlc.ListLeaderboardParams) ([]sqlc.ListLeaderboardRow, error) {
+	if s.source == SourceLive {
+		return s.db.ListLeaderboard(ctx, params)
+	}
+
+	mrows, err := s.db.ListLeaderboardMaterialized(ctx, sqlc.ListLeaderboardMaterializedParams(params))
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]sqlc.ListLeaderboardRow, 0, len(mrows))
+	for _, r := range mrows {
+		rows = append(rows, sqlc.ListLeaderboardRow{
+			ID:             r.ResumeID,
+			CurrentEloInt:  r.CurrentEloInt,
+			BattlesCount:   r.BattlesCount,
+			ImageKeyPrefix: r.ImageKeyPrefix,
+			ImageManifest:  r.ImageManifest,
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,15 @@
+package match
+
+import "math"
+
+// expectedScore is the probability that a player rated ra beats one rated rb.
+func expectedScore(ra, rb int32) float64 {
+	return 1 / (1 + math.Pow(10, float64(rb-ra)/400))
+}
+
+// eloDeltas returns the integer rating changes for A and B given A's score
+// (1 for a win, 0.5 for a draw, 0 for a loss). The update is zero-sum so ratings never drift.
+func eloDeltas(ra, rb int32, scoreA float64, k int32) (int32, int32) {
+	deltaA := int32(math.Round(float64(k) * (scoreA - expectedScore(ra, rb))))
+	return deltaA, -deltaA
+}
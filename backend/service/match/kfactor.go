@@ -0,0 +1,48 @@
+package match
+
+import "main/utils"
+
+// KFactorPolicy decides how much a single battle can move a resume's rating.
+type KFactorPolicy interface {
+	// KFactor returns K for a resume with the given number of completed battles.
+	// ratingGap is the absolute Elo difference to its opponent; policies may ignore it.
+	KFactor(battlesCount int32, ratingGap int32) int32
+}
+
+// TieredKFactorPolicy uses a high K while a resume is provisional and tapers it
+// as the resume accumulates battles.
+type TieredKFactorPolicy struct {
+	cfg utils.EloConfig
+}
+
+func NewTieredKFactorPolicy(cfg *utils.EloConfig) *TieredKFactorPolicy {
+	if cfg == nil {
+		panic("cfg must be non-nil")
+	}
+	return &TieredKFactorPolicy{cfg: *cfg}
+}
+
+func (p *TieredKFactorPolicy) KFactor(battlesCount int32, _ int32) int32 {
+	switch {
+	case battlesCount < p.cfg.ProvisionalBattles:
+		return p.cfg.ProvisionalK
+	case battlesCount < p.cfg.VeteranBattles:
+		return p.cfg.DefaultK
+	case battlesCount < p.cfg.EliteBattles:
+		return p.cfg.VeteranK
+	default:
+		return p.cfg.EliteK
+	}
+}
+
+// matchKFactor picks a single K for both sides so the update stays zero-sum.
+// The less experienced resume drives the choice, letting new resumes converge quickly.
+func matchKFactor(policy KFactorPolicy, battlesA, battlesB, eloA, eloB int32) int32 {
+	gap := eloA - eloB
+	if gap < 0 {
+		gap = -gap
+	}
+	return policy.KFactor(min(battlesA, battlesB), gap)
+}
+
+var _ KFactorPolicy = (*TieredKFactorPolicy)(nil)
@@ -0,0 +1,116 @@
+package match
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sqlc "main/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const reaperBatchSize = 500
+
+// Reaper cancels matches that were served but never voted on (e.g. the voter
+// closed the tab) so their resumes return to the pairing pool.
+type Reaper struct {
+	pool     *pgxpool.Pool
+	db       *sqlc.Queries
+	ttl      time.Duration
+	interval time.Duration
+	log      *zap.Logger
+}
+
+func NewReaper(pool *pgxpool.Pool, db *sqlc.Queries, ttl, interval time.Duration, log *zap.Logger) *Reaper {
+	if pool == nil || db == nil || log == nil {
+		panic("pool, db, and log must be non-nil")
+	}
+	return &Reaper{pool: pool, db: db, ttl: ttl, interval: interval, log: log}
+}
+
+// Run reaps on every tick until ctx is cancelled.
+func (r *Reaper) Run(ctx context.Context) {
+	r.log.Info("Starting match reaper", zap.Duration("ttl", r.ttl), zap.Duration("interval", r.interval))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.log.Info("Stopping match reaper")
+			return
+		case <-ticker.C:
+			cancelled, released, err := r.ReapOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.log.Error("Failed to reap stale matches", zap.Error(err))
+			}
+			if cancelled > 0 {
+				r.log.Info("Reaped stale matches",
+					zap.Int("cancelled_matches", cancelled),
+					zap.Int64("released_resumes", released),
+				)
+			}
+		}
+	}
+}
+
+// ReapOnce cancels every match older than the TTL, one batch per transaction,
+// and returns how many matches were cancelled and resumes released.
+func (r *Reaper) ReapOnce(ctx context.Context) (int, int64, error) {
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-r.ttl), Valid: true}
+
+	var cancelled int
+	var released int64
+	for {
+		n, m, err := r.reapBatch(ctx, cutoff)
+		cancelled += n
+		released += m
+		if err != nil {
+			return cancelled, released, err
+		}
+		if n < reaperBatchSize {
+			return cancelled, released, nil
+		}
+	}
+}
+
+func (r *Reaper) reapBatch(ctx context.Context, cutoff pgtype.Timestamptz) (int, int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := r.db.WithTx(tx)
+
+	stale, err := q.CancelStaleMatches(ctx, sqlc.CancelStaleMatchesParams{
+		Cutoff:    cutoff,
+		BatchSize: reaperBatchSize,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("cancel stale matches: %w", err)
+	}
+	if len(stale) == 0 {
+		return 0, 0, nil
+	}
+
+	resumeIDs := make([]pgtype.UUID, 0, len(stale)*2)
+	for _, m := range stale {
+		resumeIDs = append(resumeIDs, m.ResumeAID, m.ResumeBID)
+	}
+
+	released, err := q.ReleaseResumesInFlight(ctx, resumeIDs)
+	if err != nil {
+		return 0, 0, fmt.Errorf("release resumes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return len(stale), released, nil
+}
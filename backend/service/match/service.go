@@ -0,0 +1,313 @@
+package match
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/auth"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMatchNotFound  = errors.New("match not found")
+	ErrMatchNotOpen   = errors.New("match has already been resolved or cancelled")
+	ErrInvalidOutcome = errors.New("outcome must be one of a, b, draw or skip")
+	// The guest session expired or was merged into an account.
+	ErrGuestSessionClosed = errors.New("guest session is no longer active")
+	ErrSelfVote           = errors.New("cannot vote on a match containing your own resume")
+	ErrNotIssuedToVoter   = errors.New("match was not served to this voter")
+)
+
+// Reasons recorded in app.vote_violations. Mirror vote_violations_reason_valid.
+const (
+	ViolationSelfVote         = "self_vote"
+	ViolationNotIssuedToVoter = "not_issued_to_voter"
+	ViolationReplay           = "replay"
+)
+
+// violationError is a refused vote that gets recorded once its transaction has
+// rolled back. It unwraps to the error the caller sees.
+type violationError struct {
+	reason string
+	err    error
+}
+
+func (e *violationError) Error() string { return e.err.Error() }
+func (e *violationError) Unwrap() error { return e.err }
+
+// ResultObserver is told about every match result that changed ratings, after it commits.
+type ResultObserver interface {
+	MatchResolved()
+}
+
+type MatchService struct {
+	pool     *pgxpool.Pool
+	db       *sqlc.Queries
+	kPolicy  KFactorPolicy
+	observer ResultObserver
+	// Fraction of K a guest vote moves ratings by.
+	guestWeight float64
+	log         *zap.Logger
+}
+
+// NewMatchService creates the match service. observer may be nil.
+func NewMatchService(pool *pgxpool.Pool, db *sqlc.Queries, kPolicy KFactorPolicy, observer ResultObserver, guestWeight float64, log *zap.Logger) *MatchService {
+	if pool == nil || db == nil || kPolicy == nil || log == nil {
+		panic("pool, db, kPolicy, and log must be non-nil")
+	}
+	return &MatchService{pool: pool, db: db, kPolicy: kPolicy, observer: observer, guestWeight: guestWeight, log: log}
+}
+
+// Resolve applies a voter's outcome to a match. A, B and draw update both ratings
+// and mark the match resolved; skip cancels it without touching Elo. Either way
+// both resumes are released back into the pairing pool.
+// The match row is locked first, then both resumes in ID order, so a concurrent or
+// repeated call sees the match is no longer open and returns ErrMatchNotOpen.
+// Guest votes use a K scaled down by the guest weight.
+// Only the voter the match was issued to can resolve it, never on their own resume.
+// Refused votes and replays are recorded in app.vote_violations.
+func (s *MatchService) Resolve(ctx context.Context, matchID pgtype.UUID, outcome string, voter auth.Voter) (*sqlc.AppMatch, error) {
+	result, err := s.resolve(ctx, matchID, outcome, voter)
+	var violation *violationError
+	if errors.As(err, &violation) {
+		s.recordViolation(ctx, matchID, voter, violation.reason)
+	}
+	return result, err
+}
+
+func (s *MatchService) resolve(ctx context.Context, matchID pgtype.UUID, outcome string, voter auth.Voter) (*sqlc.AppMatch, error) {
+	var scoreA float64
+	switch outcome {
+	case OutcomeA:
+		scoreA = 1
+	case OutcomeB:
+		scoreA = 0
+	case OutcomeDraw:
+		scoreA = 0.5
+	case OutcomeSkip:
+	default:
+		return nil, ErrInvalidOutcome
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	if voter.IsGuest() {
+		session, err := q.GetGuestSession(ctx, voter.GuestSessionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGuestSessionClosed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get guest session: %w", err)
+		}
+		if session.MergedIntoUserID.Valid || !session.ExpiresAt.Time.After(time.Now()) {
+			return nil, ErrGuestSessionClosed
+		}
+	}
+
+	match, err := q.GetMatchByIDForUpdate(ctx, matchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get match: %w", err)
+	}
+	issued, err := issuedTo(ctx, q, match, voter)
+	if err != nil {
+		return nil, err
+	}
+	if !issued {
+		return nil, &violationError{reason: ViolationNotIssuedToVoter, err: ErrNotIssuedToVoter}
+	}
+	if !voter.IsGuest() {
+		// Pairing already skips the voter's resumes; this guards matches issued before it did.
+		own, err := q.MatchHasResumeOwnedBy(ctx, sqlc.MatchHasResumeOwnedByParams{
+			ResumeAID:   match.ResumeAID,
+			ResumeBID:   match.ResumeBID,
+			OwnerUserID: voter.UserID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("check resume owners: %w", err)
+		}
+		if own {
+			return nil, &violationError{reason: ViolationSelfVote, err: ErrSelfVote}
+		}
+	}
+	if match.State != StateCreated {
+		return nil, &violationError{reason: ViolationReplay, err: ErrMatchNotOpen}
+	}
+
+	var result *sqlc.AppMatch
+	if outcome == OutcomeSkip {
+		result, err = s.skip(ctx, q, match, voter)
+	} else {
+		result, err = s.score(ctx, q, match, outcome, scoreA, voter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	if s.observer != nil && outcome != OutcomeSkip {
+		s.observer.MatchResolved()
+	}
+
+	s.log.Info("Resolved match",
+		zap.String("match_id", result.ID.String()),
+		zap.String("outcome", outcome),
+		zap.Bool("guest", voter.IsGuest()),
+		zap.Int32("k_factor", result.KFactorUsed.Int32),
+		zap.Int32("delta_a", result.DeltaA.Int32),
+		zap.Int32("delta_b", result.DeltaB.Int32),
+	)
+
+	return result, nil
+}
+
+// score applies an Elo update for a win, loss or draw. Must run inside the
+// transaction that locked the match.
+func (s *MatchService) score(ctx context.Context, q *sqlc.Queries, match sqlc.AppMatch, outcome string, scoreA float64, voter auth.Voter) (*sqlc.AppMatch, error) {
+	var winnerResumeID, loserResumeID pgtype.UUID
+	switch outcome {
+	case OutcomeA:
+		winnerResumeID, loserResumeID = match.ResumeAID, match.ResumeBID
+	case OutcomeB:
+		winnerResumeID, loserResumeID = match.ResumeBID, match.ResumeAID
+	}
+
+	resumes, err := q.LockResumePairForUpdate(ctx, sqlc.LockResumePairForUpdateParams{
+		ResumeAID: match.ResumeAID,
+		ResumeBID: match.ResumeBID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lock resumes: %w", err)
+	}
+	if len(resumes) != 2 {
+		return nil, fmt.Errorf("lock resumes: expected 2 rows, got %d", len(resumes))
+	}
+
+	resumeA, resumeB := resumes[0], resumes[1]
+	if resumeA.ID != match.ResumeAID {
+		resumeA, resumeB = resumeB, resumeA
+	}
+
+	k := matchKFactor(s.kPolicy, resumeA.BattlesCount, resumeB.BattlesCount, resumeA.CurrentEloInt, resumeB.CurrentEloInt)
+	if voter.IsGuest() {
+		k = int32(math.Round(float64(k) * s.guestWeight))
+	}
+	deltaA, deltaB := eloDeltas(resumeA.CurrentEloInt, resumeB.CurrentEloInt, scoreA, k)
+
+	if err := q.ApplyResumeMatchResult(ctx, sqlc.ApplyResumeMatchResultParams{Delta: deltaA, ResumeID: resumeA.ID}); err != nil {
+		return nil, fmt.Errorf("apply result to resume a: %w", err)
+	}
+	if err := q.ApplyResumeMatchResult(ctx, sqlc.ApplyResumeMatchResultParams{Delta: deltaB, ResumeID: resumeB.ID}); err != nil {
+		return nil, fmt.Errorf("apply result to resume b: %w", err)
+	}
+
+	resolved, err := q.ResolveMatch(ctx, sqlc.ResolveMatchParams{
+		ID:                      match.ID,
+		WinnerResumeID:          winnerResumeID,
+		LoserResumeID:           loserResumeID,
+		DecidedByUserID:         voter.UserID,
+		KFactorUsed:             pgtype.Int4{Int32: k, Valid: true},
+		DeltaA:                  pgtype.Int4{Int32: deltaA, Valid: true},
+		DeltaB:                  pgtype.Int4{Int32: deltaB, Valid: true},
+		Outcome:                 pgtype.Text{String: outcome, Valid: true},
+		DecidedByGuestSessionID: voter.GuestSessionID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMatchNotOpen
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolve match: %w", err)
+	}
+
+	return &resolved, nil
+}
+
+// skip cancels the match and releases both resumes without changing their ratings.
+// Must run inside the transaction that locked the match.
+func (s *MatchService) skip(ctx context.Context, q *sqlc.Queries, match sqlc.AppMatch, voter auth.Voter) (*sqlc.AppMatch, error) {
+	for _, id := range []pgtype.UUID{match.ResumeAID, match.ResumeBID} {
+		if err := q.SetResumeInFlightByID(ctx, sqlc.SetResumeInFlightByIDParams{ID: id, InFlight: false}); err != nil {
+			return nil, fmt.Errorf("release resume: %w", err)
+		}
+	}
+
+	cancelled, err := q.CancelMatch(ctx, sqlc.CancelMatchParams{
+		ID:                      match.ID,
+		Outcome:                 pgtype.Text{String: OutcomeSkip, Valid: true},
+		DecidedByUserID:         voter.UserID,
+		DecidedByGuestSessionID: voter.GuestSessionID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMatchNotOpen
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cancel match: %w", err)
+	}
+
+	return &cancelled, nil
+}
+
+// issuedTo reports whether match was served to voter. A match served to a guest also
+// counts as the user's once the guest session has been merged into their account,
+// so signing in mid-match doesn't strand the open match. Until the merge lands the
+// signed-in vote is refused.
+func issuedTo(ctx context.Context, q *sqlc.Queries, match sqlc.AppMatch, voter auth.Voter) (bool, error) {
+	if voter.IsGuest() {
+		return match.IssuedToGuestSessionID.Valid && match.IssuedToGuestSessionID == voter.GuestSessionID, nil
+	}
+	if match.IssuedToUserID.Valid {
+		return match.IssuedToUserID == voter.UserID, nil
+	}
+	if !match.IssuedToGuestSessionID.Valid {
+		return false, nil
+	}
+
+	session, err := q.GetGuestSession(ctx, match.IssuedToGuestSessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get guest session: %w", err)
+	}
+	return session.MergedIntoUserID.Valid && session.MergedIntoUserID == voter.UserID, nil
+}
+
+// recordViolation writes a refused vote to the audit table. It runs after the
+// resolve transaction has rolled back, so it can't be lost with it; failures are
+// only logged.
+func (s *MatchService) recordViolation(ctx context.Context, matchID pgtype.UUID, voter auth.Voter, reason string) {
+	s.log.Warn("Refused vote",
+		zap.String("match_id", matchID.String()),
+		zap.String("reason", reason),
+		zap.Bool("guest", voter.IsGuest()),
+	)
+
+	err := s.db.CreateVoteViolation(context.WithoutCancel(ctx), sqlc.CreateVoteViolationParams{
+		MatchID:        matchID,
+		UserID:         voter.UserID,
+		GuestSessionID: voter.GuestSessionID,
+		Reason:         reason,
+	})
+	if err != nil {
+		s.log.Error("Failed to record vote violation", zap.String("match_id", matchID.String()), zap.Error(err))
+	}
+}
@@ -0,0 +1,16 @@
+package match
+
+// Lifecycle of a row in app.matches. Mirrors the matches_state_valid check constraint.
+const (
+	StateCreated   = "created"
+	StateResolved  = "resolved"
+	StateCancelled = "cancelled"
+)
+
+// How a voter decided a match. Mirrors the matches_outcome_valid check constraint.
+const (
+	OutcomeA    = "a"
+	OutcomeB    = "b"
+	OutcomeDraw = "draw"
+	OutcomeSkip = "skip"
+)
@@ -0,0 +1,174 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/auth"
+	"main/service/image"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Pairs resumes within an (industry, yoe_bucket) using Postgres only.
+// See ProjectContext.md for the seed/down/up algorithm.
+
+var (
+	ErrNoOpponent = errors.New("no opponent available")
+	// The match could never be resolved, so it isn't issued.
+	ErrGuestSessionClosed = errors.New("guest session is no longer active")
+)
+
+// Raised by matches_open_pair_unique when the same pair already has an open match.
+const uniqueViolation = "23505"
+
+type MatchedResume struct {
+	ID    pgtype.UUID
+	Pages []image.PageRenditions
+}
+
+type Match struct {
+	ID      pgtype.UUID
+	ResumeA MatchedResume
+	ResumeB MatchedResume
+}
+
+type MatchmakingService struct {
+	pool *pgxpool.Pool
+	db   *sqlc.Queries
+	log  *zap.Logger
+}
+
+func NewMatchmakingService(pool *pgxpool.Pool, db *sqlc.Queries, log *zap.Logger) *MatchmakingService {
+	if pool == nil || db == nil || log == nil {
+		panic("pool, db, and log must be non-nil")
+	}
+	return &MatchmakingService{pool: pool, db: db, log: log}
+}
+
+// CreateMatch pairs two resumes in the given bucket, flips both to in_flight and
+// records a match in state 'created' issued to voter. The voter's own resumes are
+// never served to them, and a merged or expired guest session gets nothing.
+// Everything happens in one short transaction.
+func (s *MatchmakingService) CreateMatch(ctx context.Context, industry, yoeBucket string, voter auth.Voter) (*Match, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	if voter.IsGuest() {
+		session, err := q.GetGuestSession(ctx, voter.GuestSessionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGuestSessionClosed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get guest session: %w", err)
+		}
+		if session.MergedIntoUserID.Valid || !session.ExpiresAt.Time.After(time.Now()) {
+			return nil, ErrGuestSessionClosed
+		}
+	}
+
+	candidates, err := q.PairCandidates(ctx, sqlc.PairCandidatesParams{
+		Industry:           industry,
+		YoeBucket:          yoeBucket,
+		ExcludeOwnerUserID: voter.UserID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoOpponent
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pair candidates: %w", err)
+	}
+
+	partnerID, ok := closestPartner(candidates)
+	if !ok {
+		return nil, ErrNoOpponent
+	}
+
+	for _, id := range []pgtype.UUID{candidates.SeedID, partnerID} {
+		if err := q.SetResumeInFlightByID(ctx, sqlc.SetResumeInFlightByIDParams{ID: id, InFlight: true}); err != nil {
+			return nil, fmt.Errorf("set resume in flight: %w", err)
+		}
+	}
+
+	match, err := q.CreateMatch(ctx, sqlc.CreateMatchParams{
+		ResumeAID:              candidates.SeedID,
+		ResumeBID:              partnerID,
+		Industry:               industry,
+		YoeBucket:              yoeBucket,
+		IssuedToUserID:         voter.UserID,
+		IssuedToGuestSessionID: voter.GuestSessionID,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, ErrNoOpponent
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create match: %w", err)
+	}
+
+	resumeA, err := q.GetResumeByID(ctx, match.ResumeAID)
+	if err != nil {
+		return nil, fmt.Errorf("get resume a: %w", err)
+	}
+	resumeB, err := q.GetResumeByID(ctx, match.ResumeBID)
+	if err != nil {
+		return nil, fmt.Errorf("get resume b: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.log.Info("Created match",
+		zap.String("match_id", match.ID.String()),
+		zap.String("resume_a_id", resumeA.ID.String()),
+		zap.String("resume_b_id", resumeB.ID.String()),
+		zap.String("industry", industry),
+		zap.String("yoe_bucket", yoeBucket),
+		zap.Bool("guest", voter.IsGuest()),
+	)
+
+	pagesA, err := image.ParseManifest(resumeA.ImageManifest)
+	if err != nil {
+		return nil, err
+	}
+	pagesB, err := image.ParseManifest(resumeB.ImageManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Match{
+		ID:      match.ID,
+		ResumeA: MatchedResume{ID: resumeA.ID, Pages: pagesA},
+		ResumeB: MatchedResume{ID: resumeB.ID, Pages: pagesB},
+	}, nil
+}
+
+// closestPartner picks whichever neighbour (down or up) is nearer in Elo to the seed.
+func closestPartner(c sqlc.PairCandidatesRow) (pgtype.UUID, bool) {
+	switch {
+	case !c.DownID.Valid && !c.UpID.Valid:
+		return pgtype.UUID{}, false
+	case !c.DownID.Valid:
+		return c.UpID, true
+	case !c.UpID.Valid:
+		return c.DownID, true
+	}
+
+	if c.SeedElo-c.DownElo.Int32 <= c.UpElo.Int32-c.SeedElo {
+		return c.DownID, true
+	}
+	return c.UpID, true
+}
@@ -0,0 +1,110 @@
+package processing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Channel notified by SetResumeProcessingState; the payload is the resume ID.
+const notifyChannel = "resume_processing"
+
+const reconnectDelay = 5 * time.Second
+
+// Broker listens for processing state changes in Postgres and fans them out to
+// subscribers in this process. Going through Postgres means a status stream on one
+// backend instance sees updates made by workers on any other.
+type Broker struct {
+	pool *pgxpool.Pool
+	log  *zap.Logger
+
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func NewBroker(pool *pgxpool.Pool, log *zap.Logger) *Broker {
+	if pool == nil || log == nil {
+		panic("pool and log must be non-nil")
+	}
+	return &Broker{pool: pool, log: log, subs: map[string]map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a channel that receives a signal whenever the resume's state
+// changes. Signals are coalesced; re-read the status after each one. Call the
+// returned function to unsubscribe.
+func (b *Broker) Subscribe(resumeID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subs[resumeID] == nil {
+		b.subs[resumeID] = map[chan struct{}]struct{}{}
+	}
+	b.subs[resumeID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs[resumeID], ch)
+		if len(b.subs[resumeID]) == 0 {
+			delete(b.subs, resumeID)
+		}
+	}
+}
+
+// Run holds a LISTEN connection until ctx is cancelled, reconnecting on errors.
+func (b *Broker) Run(ctx context.Context) {
+	b.log.Info("Starting processing event broker")
+
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			b.log.Info("Stopping processing event broker")
+			return
+		}
+		b.log.Error("Processing event listener failed, reconnecting", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			b.log.Info("Stopping processing event broker")
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN state is per session; take the connection out of the pool for good.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "listen "+notifyChannel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		b.publish(n.Payload)
+	}
+}
+
+func (b *Broker) publish(resumeID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[resumeID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
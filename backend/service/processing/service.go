@@ -0,0 +1,118 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sqlc "main/db/sqlc"
+	"main/service/image"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Resume processing runs outside the request that triggered it. Jobs live in
+// app.resume_jobs and are claimed by the WorkerPool with FOR UPDATE SKIP LOCKED.
+
+const (
+	// JobRender renders the preview from the stored PDF, redacts it and uploads the webp.
+	JobRender = "render"
+)
+
+// Job states.
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+)
+
+// Resume processing states, shown to the owner. Rendering and redacting come from
+// image.StageRendering and image.StageRedacting.
+const (
+	ResumeQueued    = "queued"
+	ResumeRendering = image.StageRendering
+	ResumeRedacting = image.StageRedacting
+	ResumeReady     = "ready"
+	ResumeFailed    = "failed"
+)
+
+var ErrResumeNotFound = errors.New("resume not found")
+
+// Status is what the owner sees while a resume is being processed.
+type Status struct {
+	ResumeID       pgtype.UUID
+	State          string
+	Error          string
+	ImageReady     bool
+	ImageKeyPrefix string
+	Pages          []image.PageRenditions
+}
+
+// Terminal reports whether no further updates are expected without a new job.
+func (s *Status) Terminal() bool {
+	return s.State == ResumeReady || s.State == ResumeFailed
+}
+
+type ProcessingService struct {
+	db          *sqlc.Queries
+	maxAttempts int32
+}
+
+func NewProcessingService(db *sqlc.Queries, maxAttempts int32) *ProcessingService {
+	if db == nil {
+		panic("db must be non-nil")
+	}
+	return &ProcessingService{db: db, maxAttempts: maxAttempts}
+}
+
+// EnqueueRender schedules a render of the resume's preview and marks it queued.
+// Enqueueing while a render is already waiting is a no-op; the waiting job will pick
+// up the latest options.
+func (s *ProcessingService) EnqueueRender(ctx context.Context, resumeID pgtype.UUID) error {
+	return s.EnqueueRenderTx(ctx, s.db, resumeID)
+}
+
+// EnqueueRenderTx is EnqueueRender on q, so the job is only queued if the caller's
+// transaction commits. Anything that marks a resume queued must enqueue this way, or
+// a failure in between leaves it queued with no job to run.
+func (s *ProcessingService) EnqueueRenderTx(ctx context.Context, q *sqlc.Queries, resumeID pgtype.UUID) error {
+	_, err := q.EnqueueResumeJob(ctx, sqlc.EnqueueResumeJobParams{
+		ResumeID:    resumeID,
+		Kind:        JobRender,
+		MaxAttempts: s.maxAttempts,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("enqueue render job: %w", err)
+	}
+	return nil
+}
+
+// GetStatus returns the processing status of one of the owner's resumes.
+func (s *ProcessingService) GetStatus(ctx context.Context, resumeID, ownerUserID pgtype.UUID) (*Status, error) {
+	row, err := s.db.GetResumeProcessingStatusForOwner(ctx, sqlc.GetResumeProcessingStatusForOwnerParams{
+		ID:          resumeID,
+		OwnerUserID: ownerUserID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrResumeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get processing status: %w", err)
+	}
+
+	pages, err := image.ParseManifest(row.ImageManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Status{
+		ResumeID:       row.ID,
+		State:          row.ProcessingState,
+		Error:          row.ProcessingError.String,
+		ImageReady:     row.ImageReady,
+		ImageKeyPrefix: row.ImageKeyPrefix.String,
+		Pages:          pages,
+	}, nil
+}
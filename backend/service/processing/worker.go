@@ -0,0 +1,262 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/anonymize"
+	"main/service/image"
+	"main/service/spaces"
+	"main/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+// Upper bound on a single job; also lets in-flight jobs finish during shutdown.
+const jobTimeout = 2 * time.Minute
+
+// WorkerPool claims and runs resume jobs with retries and exponential backoff.
+type WorkerPool struct {
+	db           *sqlc.Queries
+	resumeBucket *spaces.ResumeBucket
+	webpBucket   *spaces.WebpBucket
+	imageService *image.ImageService
+	cfg          *utils.ProcessingConfig
+	// Set when the leaderboard is materialized: it keeps listing superseded renditions
+	// until the next refresh, so the refresher deletes them instead.
+	deferDeletes bool
+	log          *zap.Logger
+}
+
+func NewWorkerPool(db *sqlc.Queries, resumeBucket *spaces.ResumeBucket, webpBucket *spaces.WebpBucket, imageService *image.ImageService, cfg *utils.ProcessingConfig, deferDeletes bool, log *zap.Logger) *WorkerPool {
+	if db == nil || resumeBucket == nil || webpBucket == nil || imageService == nil || cfg == nil || log == nil {
+		panic("db, resumeBucket, webpBucket, imageService, cfg, and log must be non-nil")
+	}
+	return &WorkerPool{db: db, resumeBucket: resumeBucket, webpBucket: webpBucket, imageService: imageService, cfg: cfg, deferDeletes: deferDeletes, log: log}
+}
+
+// Run starts the workers and the stuck-job sweeper and blocks until ctx is cancelled
+// and every in-flight job has finished.
+func (p *WorkerPool) Run(ctx context.Context) {
+	p.log.Info("Starting resume workers", zap.Int32("workers", p.cfg.Workers), zap.Duration("poll_interval", p.cfg.PollInterval))
+
+	var wg sync.WaitGroup
+	for i := int32(0); i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(p.cfg.StuckAfter / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			p.log.Info("Stopping resume workers")
+			return
+		case <-ticker.C:
+			cutoff := pgtype.Timestamptz{Time: time.Now().Add(-p.cfg.StuckAfter), Valid: true}
+			n, err := p.db.RequeueStuckResumeJobs(ctx, cutoff)
+			if err != nil && ctx.Err() == nil {
+				p.log.Error("Failed to requeue stuck resume jobs", zap.Error(err))
+			}
+			if n > 0 {
+				p.log.Warn("Recovered stuck resume jobs", zap.Int64("jobs", n))
+			}
+		}
+	}
+}
+
+// work claims one job at a time, sleeping for the poll interval when the queue is empty.
+func (p *WorkerPool) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := p.db.ClaimResumeJobs(ctx, 1)
+		if err != nil && ctx.Err() == nil {
+			p.log.Error("Failed to claim resume job", zap.Error(err))
+		}
+		if len(jobs) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.cfg.PollInterval):
+			}
+			continue
+		}
+		p.process(ctx, jobs[0])
+	}
+}
+
+func (p *WorkerPool) process(ctx context.Context, job sqlc.AppResumeJob) {
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobTimeout)
+	defer cancel()
+
+	log := p.log.With(
+		zap.String("job_id", job.ID.String()),
+		zap.String("resume_id", job.ResumeID.String()),
+		zap.String("kind", job.Kind),
+		zap.Int32("attempt", job.Attempts),
+	)
+
+	var err error
+	switch job.Kind {
+	case JobRender:
+		err = p.render(jobCtx, job.ResumeID)
+	default:
+		err = fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+
+	if err == nil {
+		if err := p.db.CompleteResumeJob(jobCtx, job.ID); err != nil {
+			log.Error("Failed to mark resume job complete", zap.Error(err))
+		}
+		log.Info("Processed resume job")
+		return
+	}
+
+	lastError := pgtype.Text{String: err.Error(), Valid: true}
+	// A PDF without a text layer will not get one on a retry.
+	if job.Attempts >= job.MaxAttempts || errors.Is(err, anonymize.ErrNoTextLayer) {
+		log.Error("Resume job failed permanently", zap.Error(err))
+		if err := p.db.FailResumeJob(jobCtx, sqlc.FailResumeJobParams{LastError: lastError, ID: job.ID}); err != nil {
+			log.Error("Failed to mark resume job failed", zap.Error(err))
+		}
+		p.setState(jobCtx, job.ResumeID, ResumeFailed, err.Error())
+		return
+	}
+
+	// Back to queued, keeping the error so the owner can see why it is retrying.
+	p.setState(jobCtx, job.ResumeID, ResumeQueued, err.Error())
+
+	delay := p.backoff(job.Attempts)
+	log.Warn("Resume job failed, retrying", zap.Duration("retry_in", delay), zap.Error(err))
+	if err := p.db.RetryResumeJob(jobCtx, sqlc.RetryResumeJobParams{
+		RunAt:     pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+		LastError: lastError,
+		ID:        job.ID,
+	}); err != nil {
+		log.Error("Failed to reschedule resume job", zap.Error(err))
+	}
+}
+
+// backoff doubles the base delay for each failed attempt, up to the configured cap.
+func (p *WorkerPool) backoff(attempts int32) time.Duration {
+	delay := p.cfg.RetryBaseDelay
+	for i := int32(1); i < attempts && delay < p.cfg.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.cfg.RetryMaxDelay)
+}
+
+// render rebuilds the public preview from the private PDF with the resume's current
+// anonymization options, then swaps it in and removes the previous one.
+func (p *WorkerPool) render(ctx context.Context, resumeID pgtype.UUID) error {
+	resume, err := p.db.GetResumeByID(ctx, resumeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since the job was queued; nothing left to do.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get resume: %w", err)
+	}
+
+	var pdf bytes.Buffer
+	if _, err := p.resumeBucket.StreamFileToWriter(ctx, resume.PdfStorageKey.String, &pdf); err != nil {
+		return fmt.Errorf("download pdf: %w", err)
+	}
+
+	// Previews are served with immutable caching, so every render goes to a new prefix.
+	version := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	onStage := func(stage string) { p.setState(ctx, resume.ID, stage, "") }
+	prefix, manifest, err := p.imageService.RenderRenditions(ctx, resume.ID.String(), version, pdf.Bytes(), anonymize.Options{HideCompanies: resume.HideCompanies}, onStage)
+	if err != nil {
+		return err
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+
+	previous, err := p.db.UpdateResumeRenditions(ctx, sqlc.UpdateResumeRenditionsParams{
+		ImageKeyPrefix: pgtype.Text{String: prefix, Valid: true},
+		ImageManifest:  manifestJSON,
+		ID:             resume.ID,
+		PdfStorageKey:  resume.PdfStorageKey,
+		HideCompanies:  resume.HideCompanies,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted, or a new upload, version, rollback or setting change replaced what
+		// was rendered; each of those queues its own render or restores its own
+		// renditions. Don't leave these behind.
+		p.log.Info("Discarding stale renditions", zap.String("resume_id", resume.ID.String()), zap.String("prefix", prefix))
+		return p.webpBucket.DeletePrefix(ctx, prefix)
+	}
+	if err != nil {
+		return fmt.Errorf("update renditions: %w", err)
+	}
+	if err := p.db.SetCurrentVersionRenditions(ctx, sqlc.SetCurrentVersionRenditionsParams{
+		RenderedHideCompanies: pgtype.Bool{Bool: resume.HideCompanies, Valid: true},
+		ID:                    resume.ID,
+	}); err != nil {
+		return fmt.Errorf("update version renditions: %w", err)
+	}
+	p.setState(ctx, resume.ID, ResumeReady, "")
+
+	if previous.Valid && previous.String != "" && previous.String != prefix {
+		p.deleteUnreferenced(ctx, previous.String)
+	}
+
+	return nil
+}
+
+// deleteUnreferenced removes superseded renditions unless an older version still
+// points at them (they are kept for rollback). With deferDeletes they are handed to
+// the leaderboard refresher instead.
+func (p *WorkerPool) deleteUnreferenced(ctx context.Context, prefix string) {
+	refs, err := p.db.CountResumeVersionsByImagePrefix(ctx, pgtype.Text{String: prefix, Valid: true})
+	if err != nil {
+		p.log.Warn("Failed to check renditions references", zap.String("prefix", prefix), zap.Error(err))
+		return
+	}
+	if refs > 0 {
+		return
+	}
+	if p.deferDeletes {
+		if err := p.db.DeferRenditionDeletion(ctx, prefix); err != nil {
+			p.log.Warn("Failed to defer previous renditions deletion", zap.String("prefix", prefix), zap.Error(err))
+		}
+		return
+	}
+	if err := p.webpBucket.DeletePrefix(ctx, prefix); err != nil {
+		p.log.Warn("Failed to delete previous renditions", zap.String("prefix", prefix), zap.Error(err))
+	}
+}
+
+// setState records progress for the owner. Failures are logged and otherwise ignored;
+// they must not fail the job itself.
+func (p *WorkerPool) setState(ctx context.Context, resumeID pgtype.UUID, state, errMsg string) {
+	err := p.db.SetResumeProcessingState(ctx, sqlc.SetResumeProcessingStateParams{
+		ProcessingState: state,
+		ProcessingError: pgtype.Text{String: errMsg, Valid: errMsg != ""},
+		ID:              resumeID,
+	})
+	if err != nil {
+		p.log.Warn("Failed to update processing state",
+			zap.String("resume_id", resumeID.String()),
+			zap.String("state", state),
+			zap.Error(err),
+		)
+	}
+}
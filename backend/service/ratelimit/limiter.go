@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+)
+
+// Token-bucket rate limiting for the API. Each route group has its own limit, and
+// each caller (user or IP, see middleware.RateLimit) a bucket per group.
+
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// NewLimiter limits each group in limits. Groups not listed are not limited.
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	if store == nil {
+		panic("store must be non-nil")
+	}
+	for group, limit := range limits {
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			panic(fmt.Sprintf("rate limit for group %q must have a positive rate and a burst of at least 1", group))
+		}
+	}
+	return &Limiter{store: store, limits: limits}
+}
+
+// Limit returns the limit configured for group.
+func (l *Limiter) Limit(group string) (Limit, bool) {
+	limit, ok := l.limits[group]
+	return limit, ok
+}
+
+// Allow spends one of key's tokens in group.
+func (l *Limiter) Allow(ctx context.Context, group, key string) (Result, error) {
+	limit, ok := l.limits[group]
+	if !ok {
+		return Result{Allowed: true}, nil
+	}
+	result, err := l.store.Take(ctx, group+":"+key, limit)
+	if err != nil {
+		return Result{}, fmt.Errorf("take token: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// How often idle buckets are dropped from memory.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Each instance counts on its own, so
+// behind a load balancer the effective limit is multiplied by the instance count.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	now     func() time.Time
+	log     *zap.Logger
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+func NewMemoryStore(log *zap.Logger) *MemoryStore {
+	if log == nil {
+		panic("log must be non-nil")
+	}
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: time.Now, log: log}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	var result Result
+	b.tokens, result = take(b.tokens, b.updated, now, limit)
+	b.updated = now
+	b.limit = limit
+	return result, nil
+}
+
+// Run drops buckets that have refilled completely, which behave the same as absent
+// ones, until ctx is cancelled.
+func (s *MemoryStore) Run(ctx context.Context) {
+	s.log.Info("Rate limit sweeper started", zap.Duration("interval", sweepInterval))
+	defer s.log.Info("Rate limit sweeper stopped")
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+func (s *MemoryStore) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	before := len(s.buckets)
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.log.Debug("Swept rate limit buckets", zap.Int("dropped", before-len(s.buckets)), zap.Int("kept", len(s.buckets)))
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// ---------------- Bucket stores ----------------
+
+// Store holds the token buckets. Take must refill and spend atomically per key so a
+// shared store (e.g. Postgres) gives every instance the same view of a bucket.
+type Store interface {
+	// Take refills key's bucket for the time since it was last used and spends one
+	// token if there is one.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limit is a token bucket: Rate tokens per second, holding at most Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute builds a Limit from a per-minute rate.
+func PerMinute(n float64, burst int) Limit {
+	return Limit{Rate: n / 60, Burst: burst}
+}
+
+type Result struct {
+	Allowed bool
+	// Whole tokens left after this request.
+	Remaining int
+	// How long until a token is available. Zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// take applies one request to a bucket holding tokens as of updated. Stores that keep
+// the arithmetic in Go share it.
+func take(tokens float64, updated, now time.Time, limit Limit) (float64, Result) {
+	if elapsed := now.Sub(updated).Seconds(); elapsed > 0 {
+		tokens += elapsed * limit.Rate
+	}
+	if burst := float64(limit.Burst); tokens > burst {
+		tokens = burst
+	}
+
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	return tokens, Result{RetryAfter: wait}
+}
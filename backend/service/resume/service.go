@@ -6,17 +6,21 @@ import (
 
 	sqlc "main/db/sqlc"
 	"main/service/image"
+	"main/service/processing"
 	"main/utils"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ResumeService struct {
-	db *sqlc.Queries
+	pool              *pgxpool.Pool
+	db                *sqlc.Queries
+	processingService *processing.ProcessingService
 }
 
-func NewResumeService(db *sqlc.Queries) *ResumeService {
-	return &ResumeService{db: db}
+func NewResumeService(pool *pgxpool.Pool, db *sqlc.Queries, processingService *processing.ProcessingService) *ResumeService {
+	return &ResumeService{pool: pool, db: db, processingService: processingService}
 }
 
 // Users can have up to 3 resumes.
@@ -37,13 +41,17 @@ func (s *ResumeService) FindFreeSlotForOwner(ctx context.Context, ownerUserID in
 	return s.db.FindFreeSlotForOwner(ctx, uuid)
 }
 
-func (s *ResumeService) CreateResume(ctx context.Context, ownerUserID string, name, industry, yoeBucket string, pdfMetadata *utils.PDFMetadata, imageMetadata *image.ImageMetadata) (*sqlc.AppResume, error) {
+// CreateResume inserts a resume under resumeID, which the caller picks so the PDF's
+// storage key can be derived from it before the row exists, and queues its render in
+// the same transaction.
+func (s *ResumeService) CreateResume(ctx context.Context, resumeID pgtype.UUID, ownerUserID string, name, industry, yoeBucket string, hideCompanies bool, pdfMetadata *utils.PDFMetadata, imageMetadata *image.ImageMetadata) (*sqlc.AppResume, error) {
 	builtResume, err := s.buildResume(
 		ctx,
 		ownerUserID,
 		name,
 		industry,
 		yoeBucket,
+		hideCompanies,
 		pdfMetadata,
 		imageMetadata,
 	)
@@ -52,7 +60,15 @@ func (s *ResumeService) CreateResume(ctx context.Context, ownerUserID string, na
 		return nil, err
 	}
 
-	createdResume, err := s.db.CreateResumeWithSlot(ctx, sqlc.CreateResumeWithSlotParams{
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	createdResume, err := q.CreateResumeWithSlot(ctx, sqlc.CreateResumeWithSlotParams{
 		OwnerUserID:   builtResume.OwnerUserID,
 		Slot:          builtResume.Slot,
 		Name:          builtResume.Name,
@@ -64,12 +80,28 @@ func (s *ResumeService) CreateResume(ctx context.Context, ownerUserID string, na
 		ImageKeyPrefix: builtResume.ImageKeyPrefix,
 		Column10:      builtResume.PageCount,
 		Column11:      builtResume.ImageReady,
+		HideCompanies: builtResume.HideCompanies,
+		ID:            resumeID,
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	// Version 1 is recorded up front so the history is complete from the first upload.
+	if err := q.SnapshotCurrentResumeVersion(ctx, createdResume.ID); err != nil {
+		return nil, err
+	}
+
+	// Rendering and redaction happen in the background; image_ready flips once the preview is up.
+	if err := s.processingService.EnqueueRenderTx(ctx, q, createdResume.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
 	return &createdResume, nil
 }
 
@@ -136,7 +168,46 @@ func (s *ResumeService) UpdateImageMetadataForResume(ctx context.Context, userID
 	return err
 }
 
-func (s *ResumeService) buildResume(ctx context.Context, ownerUserID string, name, industry, yoeBucket string, pdfMetadata *utils.PDFMetadata, imageMetadata *image.ImageMetadata) (*sqlc.AppResume, error) {
+// UpdateHideCompanies changes whether one of the owner's previews masks employer names
+// and queues the re-render in the same transaction. The row is read back after the
+// enqueue so it carries the queued processing state.
+func (s *ResumeService) UpdateHideCompanies(ctx context.Context, resumeID, ownerUserID pgtype.UUID, hideCompanies bool) (*sqlc.AppResume, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	if _, err := q.UpdateResumeHideCompanies(ctx, sqlc.UpdateResumeHideCompaniesParams{
+		ID:            resumeID,
+		OwnerUserID:   ownerUserID,
+		HideCompanies: hideCompanies,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.processingService.EnqueueRenderTx(ctx, q, resumeID); err != nil {
+		return nil, err
+	}
+
+	resume, err := q.GetResumeByIDForOwner(ctx, sqlc.GetResumeByIDForOwnerParams{
+		ID:          resumeID,
+		OwnerUserID: ownerUserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return &resume, nil
+}
+
+func (s *ResumeService) buildResume(ctx context.Context, ownerUserID string, name, industry, yoeBucket string, hideCompanies bool, pdfMetadata *utils.PDFMetadata, imageMetadata *image.ImageMetadata) (*sqlc.AppResume, error) {
 	userID, err := utils.ConvertStringToUUID(ownerUserID)
 	if err != nil {
 		return nil, err
@@ -162,6 +233,7 @@ func (s *ResumeService) buildResume(ctx context.Context, ownerUserID string, nam
 		CurrentEloInt: 1000,	
 		BattlesCount:  0,
 		InFlight:      false,
+		HideCompanies: hideCompanies,
 	}
 
 	return resume, nil
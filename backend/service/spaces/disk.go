@@ -0,0 +1,149 @@
+package spaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"main/utils"
+)
+
+// DiskStore keeps objects as files under a root directory, one file per key, for
+// local development without an S3 endpoint. Content types are inferred from the
+// key's extension.
+type DiskStore struct {
+	name string
+	root string
+}
+
+func NewDiskStore(name, root string) (*DiskStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create storage root: %w", err)
+	}
+	return &DiskStore{name: name, root: root}, nil
+}
+
+func (s *DiskStore) Name() string { return s.name }
+
+// path maps a key to its file, refusing keys that would escape the root or that
+// can't be a file name (e.g. a trailing slash).
+func (s *DiskStore) path(key string) (string, error) {
+	if key == "" || strings.HasSuffix(key, "/") || !fs.ValidPath(key) {
+		return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *DiskStore) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	var objs []Object
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objs = append(objs, s.object(key, info))
+		return nil
+	})
+	return objs, err
+}
+
+func (s *DiskStore) PutObject(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Written to a temp file and renamed so readers never see a partial object.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *DiskStore) HeadObject(ctx context.Context, key string) (*Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj := s.object(key, info)
+	return &obj, nil
+}
+
+func (s *DiskStore) StreamFileToWriter(ctx context.Context, key string, w io.Writer) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrObjectNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+func (s *DiskStore) DeleteObjects(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		path, err := s.path(key)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DiskStore) object(key string, info fs.FileInfo) Object {
+	return Object{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		ContentType:  utils.MimeTypeForFilename(key, "application/octet-stream"),
+	}
+}
+
+var _ ObjectStore = (*DiskStore)(nil)
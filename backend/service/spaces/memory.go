@@ -0,0 +1,92 @@
+package spaces
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps objects in a map. Contents are lost on restart; meant for tests
+// and throwaway local runs.
+type MemoryStore struct {
+	name    string
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info Object
+}
+
+func NewMemoryStore(name string) *MemoryStore {
+	return &MemoryStore{name: name, objects: make(map[string]memoryObject)}
+}
+
+func (s *MemoryStore) Name() string { return s.name }
+
+func (s *MemoryStore) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var objs []Object
+	for key, o := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objs = append(objs, o.info)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Key < objs[j].Key })
+	return objs, nil
+}
+
+func (s *MemoryStore) PutObject(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = memoryObject{
+		data: data,
+		info: Object{Key: key, Size: int64(len(data)), LastModified: time.Now(), ContentType: opts.ContentType},
+	}
+	return nil
+}
+
+func (s *MemoryStore) HeadObject(ctx context.Context, key string) (*Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o, ok := s.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	info := o.info
+	return &info, nil
+}
+
+func (s *MemoryStore) StreamFileToWriter(ctx context.Context, key string, w io.Writer) (int64, error) {
+	s.mu.RLock()
+	o, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return 0, ErrObjectNotFound
+	}
+	return io.Copy(w, bytes.NewReader(o.data))
+}
+
+func (s *MemoryStore) DeleteObjects(ctx context.Context, keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.objects, key)
+	}
+	return nil
+}
+
+var _ ObjectStore = (*MemoryStore)(nil)
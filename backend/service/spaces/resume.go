@@ -3,79 +3,125 @@ package spaces
 import (
 	"context"
 	"fmt"
+	"io"
 	"main/utils"
 	"mime/multipart"
-	"sync"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"go.uber.org/zap"
 )
 
 // ---------------- Resume bucket clients ----------------
 
-// Data is stored in the format: {userId}/resumes/{resumeId}/{ver}/
+// Data is stored in the format: {userId}/resumes/{resumeId}/{sha256}.pdf
+// Keys never depend on the user-editable name; the real key is always read from pdf_storage_key.
 
 type ResumeBucket struct {
-	BucketClient
+	ObjectStore
+	presignTTL time.Duration
+	log        *zap.Logger
 }
 
 // IMPORTANT: Methods in this bucket take into account the format of the objects in the bucket.
 type ResumeBucketOps interface {
 	DeleteResume(ctx context.Context, pdfStorageKey string) error
-	UploadResumeAsset(ctx context.Context, userID, resumeName string, file *multipart.FileHeader) error
-	Prefix(userID, resumeName string) string
+	UploadPDF(ctx context.Context, key string, file *multipart.FileHeader) error
+	PutPDF(ctx context.Context, key string, body io.Reader) error
+	PresignedURL(ctx context.Context, key, filename string) (string, time.Time, error)
+	PresignedUpload(ctx context.Context, key string, ttl time.Duration) (*PresignedPost, time.Time, error)
+	UploadKey(userID, resumeID string) string
+	ContentKey(userID, resumeID, contentHash string) string
+	ResumePrefix(userID, resumeID string) string
+	DeletePrefix(ctx context.Context, prefix string) error
 	ValidateResumeFile(file *multipart.FileHeader) (*utils.PDFMetadata, error)
 }
 
-// Singleton pattern to ensure only one instance of the resume bucket is created.
-var (
-	resumeOnce   sync.Once
-	resumeBucket *ResumeBucket
-	resumeErr    error
-)
+// NewResumeBucket wraps store; presigned links to PDFs expire after presignTTL.
+func NewResumeBucket(store ObjectStore, presignTTL time.Duration, log *zap.Logger) *ResumeBucket {
+	if store == nil || log == nil {
+		panic("store and log must be non-nil")
+	}
+	return &ResumeBucket{ObjectStore: store, presignTTL: presignTTL, log: log}
+}
 
-func GetResumeBucket(ctx context.Context, log *zap.Logger, config *utils.Config) (*ResumeBucket, error) {
-	resumeOnce.Do(func() {
-		var base *BucketClient
-		base, resumeErr = createBucketClient(ctx, config.Resume.BucketName, config.Bucket.BucketRegion, config.Bucket.BucketEndpoint, config.Resume.AccessKeyID, config.Resume.AccessKeySecret, log)
-		if resumeErr != nil {
-			log.Error("Failed to create resume bucket", zap.Error(resumeErr))
-			return
-		}
-		resumeBucket = &ResumeBucket{BucketClient: *base}
-	})
-	return resumeBucket, resumeErr
+// PresignedURL returns a short-lived link that downloads the PDF at key as filename,
+// and when it expires. Only hand these to the resume's owner.
+func (b *ResumeBucket) PresignedURL(ctx context.Context, key, filename string) (string, time.Time, error) {
+	presigner, ok := b.ObjectStore.(Presigner)
+	if !ok {
+		return "", time.Time{}, ErrPresignUnsupported
+	}
+	expiresAt := time.Now().Add(b.presignTTL)
+	url, err := presigner.PresignGet(ctx, key, b.presignTTL, filename)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return url, expiresAt, nil
 }
 
-func (b *ResumeBucket) Prefix(userID, resumeName string) string {
-	base := fmt.Sprintf("%s/resumes", userID)
-	if resumeName != "" {
-		base = fmt.Sprintf("%s/%s", base, resumeName)
+// PresignedUpload returns a form the browser can POST a resume PDF to, limited to
+// MAX_RESUME_FILE_SIZE and application/pdf, and when it expires.
+func (b *ResumeBucket) PresignedUpload(ctx context.Context, key string, ttl time.Duration) (*PresignedPost, time.Time, error) {
+	presigner, ok := b.ObjectStore.(Presigner)
+	if !ok {
+		return nil, time.Time{}, ErrPresignUnsupported
+	}
+	expiresAt := time.Now().Add(ttl)
+	post, err := presigner.PresignPost(ctx, key, ttl, UploadPolicy{ContentType: "application/pdf", MaxSize: utils.MAX_RESUME_FILE_SIZE})
+	if err != nil {
+		return nil, time.Time{}, err
 	}
-	
-	return base + "/"
+	return post, expiresAt, nil
 }
 
+// UploadKey is the staging key of a direct upload. Finalize copies accepted files to
+// their ContentKey; anything left here is collected as an orphan.
+func (b *ResumeBucket) UploadKey(userID, resumeID string) string {
+	return fmt.Sprintf("%s/uploads/%s.pdf", userID, resumeID)
+}
 
-// Delete an entire resume (all versions).
-func (b *ResumeBucket) DeleteResume(ctx context.Context, pdfStorageKey string) error {
-	objs := []types.Object{
-		{
-			Key: aws.String(pdfStorageKey),
-		},
+// ContentKey is where a resume file lives: derived from the resume ID and the file's
+// hash, so renames don't move it and re-uploading the same file to a resume reuses
+// its object. Different resumes never share one.
+func (b *ResumeBucket) ContentKey(userID, resumeID, contentHash string) string {
+	return fmt.Sprintf("%s/resumes/%s/%s.pdf", userID, resumeID, contentHash)
+}
+
+// ResumePrefix holds every content-addressed file of one resume, across all versions.
+func (b *ResumeBucket) ResumePrefix(userID, resumeID string) string {
+	return fmt.Sprintf("%s/resumes/%s/", userID, resumeID)
+}
+
+// DeletePrefix removes every object under a ResumePrefix.
+func (b *ResumeBucket) DeletePrefix(ctx context.Context, prefix string) error {
+	// An empty or short prefix would match other resumes (or the whole bucket).
+	if !strings.Contains(prefix, "/resumes/") || !strings.HasSuffix(prefix, "/") || strings.Count(prefix, "/") < 3 {
+		return fmt.Errorf("refusing to delete unscoped resume prefix %q", prefix)
 	}
-	return b.deleteInChunks(ctx, objs)
+
+	objs, err := b.ListObjects(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list resume objects: %w", err)
+	}
+	return b.DeleteObjects(ctx, objectKeys(objs))
 }
 
-// Upload a single file into a specific resume version under the given key name (relative to the version prefix).
-func (b *ResumeBucket) UploadResumeAsset(ctx context.Context, userID, resumeName string, file *multipart.FileHeader) error {
-	fullKey := b.Prefix(userID, resumeName)
+// IsContentKey reports whether key already follows the ContentKey layout.
+func IsContentKey(key string) bool {
+	return contentKeyRe.MatchString(key)
+}
 
-	ct := utils.MimeTypeForFilename(file.Filename, "application/pdf")
+var contentKeyRe = regexp.MustCompile(`^[0-9a-f-]{36}/resumes/[0-9a-f-]{36}/[0-9a-f]{64}\.pdf$`)
+
+// Delete an entire resume (all versions).
+func (b *ResumeBucket) DeleteResume(ctx context.Context, pdfStorageKey string) error {
+	return b.DeleteObjects(ctx, []string{pdfStorageKey})
+}
 
+// UploadPDF uploads a resume file under the given full key and waits for it to be readable.
+func (b *ResumeBucket) UploadPDF(ctx context.Context, fullKey string, file *multipart.FileHeader) error {
 	// Open the uploaded file
 	src, err := file.Open()
 	if err != nil {
@@ -83,29 +129,14 @@ func (b *ResumeBucket) UploadResumeAsset(ctx context.Context, userID, resumeName
 	}
 	defer src.Close()
 
-	_, err = b.Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(b.Name),
-		Key:         aws.String(fullKey),
-		Body:        src,
-		ContentType: aws.String(ct),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to upload file to S3: %w", err)
-	}
-
-	// Best-effort waiter
-
-	waitCtx, cancel := context.WithTimeout(ctx, 2 * time.Second)
-	defer cancel()
+	return b.PutPDF(ctx, fullKey, src)
+}
 
-	if err := s3.NewObjectExistsWaiter(b.Client).Wait(waitCtx, &s3.HeadObjectInput{
-		Bucket: aws.String(b.Name),
-		Key:    aws.String(fullKey),
-	}, 10 * time.Second); err != nil { // waiter’s backoff ceiling; waitCtx will cut it short
-		b.log.Warn("Failed to wait for object to exist", zap.Error(err))
-		return err
+// PutPDF uploads a PDF body under the given full key and waits for it to be readable.
+func (b *ResumeBucket) PutPDF(ctx context.Context, fullKey string, body io.Reader) error {
+	if err := b.PutObject(ctx, fullKey, body, PutOptions{ContentType: "application/pdf", Wait: true}); err != nil {
+		return fmt.Errorf("failed to upload resume file: %w", err)
 	}
-
 	return nil
 }
 
@@ -0,0 +1,269 @@
+package spaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"go.uber.org/zap"
+)
+
+// ---------------- S3 driver ----------------
+
+// S3Store talks to any S3-compatible endpoint (DigitalOcean Spaces in production).
+type S3Store struct {
+	Client *s3.Client
+	name   string
+	log    *zap.Logger
+}
+
+// NewS3Store creates a client for one bucket with the given configuration
+func NewS3Store(ctx context.Context, bucketName string, region string, endpoint string, accessKeyID string, accessKeySecret string, log *zap.Logger) (*S3Store, error) {
+	if bucketName == "" || accessKeyID == "" || accessKeySecret == "" {
+		return nil, fmt.Errorf("bucketName, accessKeyID, and accessKeySecret must be set")
+	}
+
+	if region == "" || endpoint == "" {
+		return nil, fmt.Errorf("region and endpoint must be set")
+	}
+
+	baseCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := endpoint
+
+	store := &S3Store{
+		Client: s3.NewFromConfig(baseCfg, func(o *s3.Options) {
+			o.BaseEndpoint = aws.String(baseURL)
+			o.UsePathStyle = true
+			o.Credentials = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
+				accessKeyID,
+				accessKeySecret,
+				"",
+			))
+		}),
+		name: bucketName,
+		log:  log,
+	}
+
+	return store, nil
+}
+
+func (s *S3Store) Name() string { return s.name }
+
+// ListObjects lists the objects under a prefix, following every page
+func (s *S3Store) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.name),
+		Prefix: aws.String(prefix),
+	}
+	var objects []Object
+	objectPaginator := s3.NewListObjectsV2Paginator(s.Client, input)
+	for objectPaginator.HasMorePages() {
+		output, err := objectPaginator.NextPage(ctx)
+		if err != nil {
+			var noBucket *types.NoSuchBucket
+			if errors.As(err, &noBucket) {
+				s.log.Error("Bucket does not exist", zap.String("bucket", s.name))
+				err = noBucket
+			}
+			return objects, err
+		}
+		for _, o := range output.Contents {
+			if o.Key == nil {
+				continue
+			}
+			obj := Object{Key: *o.Key, Size: aws.ToInt64(o.Size)}
+			if o.LastModified != nil {
+				obj.LastModified = *o.LastModified
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// PutObject uploads body under key, optionally waiting for it to be readable.
+func (s *S3Store) PutObject(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.CacheControl != "" {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if opts.Public {
+		input.ACL = types.ObjectCannedACLPublicRead
+	}
+
+	if _, err := s.Client.PutObject(ctx, input); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "EntityTooLarge" {
+			s.log.Error("Object is too large for upload",
+				zap.String("bucket", s.name),
+				zap.String("message", "To upload objects larger than 5GB, use the S3 console (160GB max) or the multipart upload API (5TB max)"))
+		} else {
+			s.log.Error("Couldn't upload object",
+				zap.String("bucket", s.name),
+				zap.String("objectKey", key),
+				zap.Error(err))
+		}
+		return fmt.Errorf("failed to upload object: %w", err)
+	}
+
+	if !opts.Wait {
+		return nil
+	}
+
+	// Best-effort waiter
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := s3.NewObjectExistsWaiter(s.Client).Wait(waitCtx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(key),
+	}, 10*time.Second); err != nil { // waiter’s backoff ceiling; waitCtx will cut it short
+		s.log.Warn("Failed to wait for object to exist", zap.String("objectKey", key), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *S3Store) HeadObject(ctx context.Context, key string) (*Object, error) {
+	out, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	obj := &Object{Key: key, Size: aws.ToInt64(out.ContentLength), ContentType: aws.ToString(out.ContentType)}
+	if out.LastModified != nil {
+		obj.LastModified = *out.LastModified
+	}
+	return obj, nil
+}
+
+func (s *S3Store) StreamFileToWriter(ctx context.Context, key string, w io.Writer) (int64, error) {
+	result, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		s.log.Error("Failed to download file from bucket",
+			zap.String("bucket", s.name),
+			zap.String("objectKey", key),
+			zap.Error(err),
+		)
+		var noKey *types.NoSuchKey
+		if errors.As(err, &noKey) {
+			return 0, ErrObjectNotFound
+		}
+		return 0, err
+	}
+	defer result.Body.Close()
+
+	return io.Copy(w, result.Body)
+}
+
+// DeleteObjects deletes keys from the bucket, at most 1000 per call as S3 requires.
+func (s *S3Store) DeleteObjects(ctx context.Context, keys []string) error {
+	const maxPerCall = 1000
+	for i := 0; i < len(keys); i += maxPerCall {
+		end := min(i+maxPerCall, len(keys))
+		if err := s.deleteBatch(ctx, keys[i:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) deleteBatch(ctx context.Context, keys []string) error {
+	objectIds := make([]types.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(key)})
+	}
+	output, err := s.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(s.name),
+		Delete: &types.Delete{Objects: objectIds, Quiet: aws.Bool(true)},
+	})
+	if err != nil {
+		s.log.Error("Error deleting objects from bucket", zap.String("bucket", s.name), zap.Error(err))
+		var noBucket *types.NoSuchBucket
+		if errors.As(err, &noBucket) {
+			s.log.Error("Bucket does not exist", zap.String("bucket", s.name))
+			return noBucket
+		}
+		return err
+	}
+	if len(output.Errors) > 0 {
+		for _, outErr := range output.Errors {
+			s.log.Error("Delete object error",
+				zap.String("key", aws.ToString(outErr.Key)),
+				zap.String("message", aws.ToString(outErr.Message)))
+		}
+		return fmt.Errorf("%s", aws.ToString(output.Errors[0].Message))
+	}
+	return nil
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, key string, ttl time.Duration, filename string) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(key),
+	}
+	if filename != "" {
+		input.ResponseContentDisposition = aws.String(fmt.Sprintf("attachment; filename=%q", filename))
+	}
+
+	req, err := s3.NewPresignClient(s.Client).PresignGetObject(ctx, input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("presign get: %w", err)
+	}
+	return req.URL, nil
+}
+
+func (s *S3Store) PresignPost(ctx context.Context, key string, ttl time.Duration, policy UploadPolicy) (*PresignedPost, error) {
+	req, err := s3.NewPresignClient(s.Client).PresignPostObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.name),
+		Key:    aws.String(key),
+	}, func(o *s3.PresignPostOptions) {
+		o.Expires = ttl
+		o.Conditions = []interface{}{
+			[]interface{}{"content-length-range", 1, policy.MaxSize},
+			map[string]string{"Content-Type": policy.ContentType},
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("presign post: %w", err)
+	}
+
+	// The policy pins Content-Type, so the form has to send exactly that value.
+	req.Values["Content-Type"] = policy.ContentType
+	return &PresignedPost{URL: req.URL, Fields: req.Values}, nil
+}
+
+var (
+	_ ObjectStore = (*S3Store)(nil)
+	_ Presigner   = (*S3Store)(nil)
+)
@@ -0,0 +1,113 @@
+package spaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"main/utils"
+
+	"go.uber.org/zap"
+)
+
+// ---------------- Object storage drivers ----------------
+
+// ObjectStore is the raw object storage a bucket sits on. ResumeBucket and WebpBucket
+// add the key layouts on top; the driver is picked by STORAGE_DRIVER.
+type ObjectStore interface {
+	Name() string
+	// ListObjects returns every object whose key starts with prefix.
+	ListObjects(ctx context.Context, prefix string) ([]Object, error)
+	PutObject(ctx context.Context, key string, body io.Reader, opts PutOptions) error
+	HeadObject(ctx context.Context, key string) (*Object, error)
+	StreamFileToWriter(ctx context.Context, key string, w io.Writer) (int64, error)
+	// DeleteObjects removes any number of keys; keys that don't exist are ignored.
+	DeleteObjects(ctx context.Context, keys []string) error
+}
+
+// Returned by HeadObject and StreamFileToWriter when the key does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Returned when the store refuses a key it cannot map to an object, e.g. one with "..".
+var ErrInvalidKey = errors.New("invalid object key")
+
+// Returned when the store has no way to hand out direct links; callers fall back to proxying.
+var ErrPresignUnsupported = errors.New("storage driver cannot presign URLs")
+
+// Presigner is implemented by stores that can issue time-limited links to private objects.
+type Presigner interface {
+	// PresignGet returns a GET URL valid for ttl. A non-empty filename makes
+	// browsers download the object under that name.
+	PresignGet(ctx context.Context, key string, ttl time.Duration, filename string) (string, error)
+	// PresignPost returns a browser form upload to key, valid for ttl, that the
+	// store itself rejects when it breaks policy.
+	PresignPost(ctx context.Context, key string, ttl time.Duration, policy UploadPolicy) (*PresignedPost, error)
+}
+
+// UploadPolicy limits what a presigned upload may write.
+type UploadPolicy struct {
+	ContentType string
+	MaxSize     int64
+}
+
+// PresignedPost is a multipart/form-data POST: send Fields, then the file as "file".
+type PresignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ContentType  string
+}
+
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+	// Readable without credentials. Only meaningful for S3.
+	Public bool
+	// Block until the object is readable. Only meaningful for S3.
+	Wait bool
+}
+
+func objectKeys(objs []Object) []string {
+	keys := make([]string, len(objs))
+	for i, o := range objs {
+		keys[i] = o.Key
+	}
+	return keys
+}
+
+const (
+	DriverS3     = "s3"
+	DriverDisk   = "disk"
+	DriverMemory = "memory"
+)
+
+// OpenResumeStore opens the store backing the private resume bucket.
+func OpenResumeStore(ctx context.Context, config *utils.Config, log *zap.Logger) (ObjectStore, error) {
+	return openStore(ctx, config, config.Resume.BucketName, config.Resume.AccessKeyID, config.Resume.AccessKeySecret, log)
+}
+
+// OpenWebpStore opens the store backing the public webp bucket.
+func OpenWebpStore(ctx context.Context, config *utils.Config, log *zap.Logger) (ObjectStore, error) {
+	return openStore(ctx, config, config.Webp.BucketName, config.Webp.AccessKeyID, config.Webp.AccessKeySecret, log)
+}
+
+func openStore(ctx context.Context, config *utils.Config, bucketName, accessKeyID, accessKeySecret string, log *zap.Logger) (ObjectStore, error) {
+	switch config.Bucket.Driver {
+	case DriverS3:
+		return NewS3Store(ctx, bucketName, config.Bucket.BucketRegion, config.Bucket.BucketEndpoint, accessKeyID, accessKeySecret, log)
+	case DriverDisk:
+		return NewDiskStore(bucketName, filepath.Join(config.Bucket.DiskRoot, bucketName))
+	case DriverMemory:
+		return NewMemoryStore(bucketName), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", config.Bucket.Driver)
+	}
+}
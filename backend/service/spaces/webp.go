@@ -4,67 +4,69 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"main/utils"
-	"sync"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"go.uber.org/zap"
 )
 
 // ---------------- Webp bucket clients ----------------
 
-// Data is stored in the format: resumes/{resumeId}/{ver}-{hash}/
-// Under this, there are webp files of varying sizes.
-// The hash is used to avoid overly aggressive CDN caching between different versions of the same resume.
+// Data is stored in the format: resumes/{resumeId}/{ver}/
+// Under this, there are webp files of varying sizes and a manifest.
+// Every render gets a new version so CDN caching never serves a stale preview.
+// Keys are public, so they must not contain the owner's user ID. Renditions from
+// before that were stored under users/{userId}/resumes/{resumeId}/ and are only
+// ever deleted.
 
 type WebpBucket struct {
-	BucketClient
+	ObjectStore
+	baseURL string
+	log     *zap.Logger
 }
 
-// Singleton pattern to ensure only one instance of the webp bucket is created.
-var (
-	webpOnce   sync.Once
-	webpBucket *WebpBucket
-	webpErr    error
-)
-
 // IMPORTANT: Methods in this bucket take into account the format of the objects in the bucket.
 type WebpBucketOps interface {
-	Prefix(userID, resumeID, objectName string) string
-	UploadBytes(ctx context.Context, userID, resumeID, objectName string, data []byte, contentType string) error
+	Prefix(resumeID, objectName string) string
+	UploadBytes(ctx context.Context, resumeID, objectName string, data []byte, contentType string) error
 	DeleteWebp(ctx context.Context, imageKeyPrefix string) error
+	DeletePrefix(ctx context.Context, imageKeyPrefix string) error
 }
 
-func GetWebpBucket(ctx context.Context, log *zap.Logger, config *utils.Config) (*WebpBucket, error) {
-	webpOnce.Do(func() {
-		var base *BucketClient
-		base, webpErr = createBucketClient(ctx, config.Webp.BucketName, config.Bucket.BucketRegion, config.Bucket.BucketEndpoint, config.Webp.AccessKeyID, config.Webp.AccessKeySecret, log)
-		if webpErr != nil {
-			log.Error("Failed to create webp bucket", zap.Error(webpErr))
-			return
-		}
-		webpBucket = &WebpBucket{BucketClient: *base}
-	})
-	return webpBucket, webpErr
+// NewWebpBucket wraps store; public URLs are built by appending keys to baseURL (the CDN host).
+func NewWebpBucket(store ObjectStore, baseURL string, log *zap.Logger) *WebpBucket {
+	if store == nil || log == nil {
+		panic("store and log must be non-nil")
+	}
+	return &WebpBucket{ObjectStore: store, baseURL: strings.TrimRight(baseURL, "/"), log: log}
+}
+
+// URL is the public (CDN) address of a webp object. Objects are public-read and
+// immutable, so these never expire.
+func (b *WebpBucket) URL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return b.baseURL + "/" + strings.TrimLeft(key, "/")
+}
+
+func (b *WebpBucket) Prefix(resumeID, objectName string) string {
+	// Data format: resumes/{resumeId}/{objectName}
+	return fmt.Sprintf("resumes/%s/%s", resumeID, objectName)
 }
 
-func (b *WebpBucket) Prefix(userID, resumeID, objectName string) string {
-	// Data format: users/{userID}/resumes/{resumeId}/{objectName}
-	return fmt.Sprintf("users/%s/resumes/%s/%s", userID, resumeID, objectName)
+// LegacyPrefix is where a resume's renditions were stored before keys left out the
+// owner's user ID.
+func (b *WebpBucket) LegacyPrefix(userID, resumeID string) string {
+	return fmt.Sprintf("users/%s/resumes/%s/", userID, resumeID)
 }
 
-func (b *WebpBucket) UploadBytes(ctx context.Context, userID, resumeID, objectName string, data []byte, contentType string) error {
-	fullKey := b.Prefix(userID, resumeID, objectName)
+func (b *WebpBucket) UploadBytes(ctx context.Context, resumeID, objectName string, data []byte, contentType string) error {
+	fullKey := b.Prefix(resumeID, objectName)
 
-	_, err := b.Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(b.Name),
-		Key:         aws.String(fullKey),
-		Body:       bytes.NewReader(data),
-		ContentType: aws.String(contentType),
-		CacheControl: aws.String("public, max-age=31536000, immutable"),
-		ACL: 	  types.ObjectCannedACLPublicRead,
+	err := b.PutObject(ctx, fullKey, bytes.NewReader(data), PutOptions{
+		ContentType:  contentType,
+		CacheControl: "public, max-age=31536000, immutable",
+		Public:       true,
 	})
 	if err != nil {
 		b.log.Error("Failed to upload bytes to webp bucket", 
@@ -77,11 +79,7 @@ func (b *WebpBucket) UploadBytes(ctx context.Context, userID, resumeID, objectNa
 }
 
 func (b *WebpBucket) DeleteWebp(ctx context.Context, imageKeyPrefix string) error {
-	_, err := b.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(b.Name),
-		Key: aws.String(imageKeyPrefix),
-	})
-	if err != nil {
+	if err := b.DeleteObjects(ctx, []string{imageKeyPrefix}); err != nil {
 		b.log.Error("Failed to delete webp from webp bucket", 
 			zap.String("key", imageKeyPrefix),
 			zap.Error(err))
@@ -91,4 +89,20 @@ func (b *WebpBucket) DeleteWebp(ctx context.Context, imageKeyPrefix string) erro
 	return nil
 }
 
+// DeletePrefix removes every object under a rendition prefix: all pages, widths and the manifest.
+func (b *WebpBucket) DeletePrefix(ctx context.Context, imageKeyPrefix string) error {
+	// An empty or short prefix would match other resumes (or the whole bucket).
+	scoped := strings.HasPrefix(imageKeyPrefix, "resumes/") && strings.Count(imageKeyPrefix, "/") >= 2 ||
+		strings.HasPrefix(imageKeyPrefix, "users/") && strings.Count(imageKeyPrefix, "/") >= 4
+	if !scoped {
+		return fmt.Errorf("refusing to delete unscoped webp prefix %q", imageKeyPrefix)
+	}
+
+	objs, err := b.ListObjects(ctx, imageKeyPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list webp objects: %w", err)
+	}
+	return b.DeleteObjects(ctx, objectKeys(objs))
+}
+
 var _ WebpBucketOps = (*WebpBucket)(nil)
\ No newline at end of file
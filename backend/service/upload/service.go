@@ -0,0 +1,271 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlc "main/db/sqlc"
+	"main/service/processing"
+	"main/service/spaces"
+	"main/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Direct-to-bucket uploads: the browser POSTs the PDF straight to storage with a
+// presigned form, then asks us to finalize. The slot is held by an upload intent in
+// between so a concurrent upload can't take it.
+
+var (
+	ErrNoFreeSlot      = errors.New("no free resume slot")
+	ErrIntentNotFound  = errors.New("upload intent not found")
+	ErrUploadMissing   = errors.New("file has not been uploaded")
+	ErrUploadsDisabled = errors.New("direct uploads are not supported by this storage driver")
+)
+
+// RejectedError is returned by Finalize when the uploaded file fails validation. The
+// object and the intent are already gone by then.
+type RejectedError struct {
+	Reason error
+}
+
+func (e *RejectedError) Error() string { return e.Reason.Error() }
+func (e *RejectedError) Unwrap() error { return e.Reason }
+
+const uniqueViolation = "23505"
+
+// How long after the form expires a finished upload can still be finalized.
+const finalizeWindow = 10 * time.Minute
+
+// Details of the resume to create, fixed when the slot is reserved.
+type IntentParams struct {
+	Name          string
+	Industry      string
+	YoeBucket     string
+	HideCompanies bool
+}
+
+type Intent struct {
+	ResumeID  pgtype.UUID
+	Slot      int16
+	Upload    *spaces.PresignedPost
+	ExpiresAt time.Time
+}
+
+type UploadService struct {
+	pool              *pgxpool.Pool
+	db                *sqlc.Queries
+	resumeBucket      *spaces.ResumeBucket
+	processingService *processing.ProcessingService
+	ttl               time.Duration
+	log               *zap.Logger
+}
+
+func NewUploadService(pool *pgxpool.Pool, db *sqlc.Queries, resumeBucket *spaces.ResumeBucket, processingService *processing.ProcessingService, ttl time.Duration, log *zap.Logger) *UploadService {
+	if pool == nil || db == nil || resumeBucket == nil || processingService == nil || log == nil {
+		panic("pool, db, resumeBucket, processingService, and log must be non-nil")
+	}
+	return &UploadService{pool: pool, db: db, resumeBucket: resumeBucket, processingService: processingService, ttl: ttl, log: log}
+}
+
+// CreateIntent reserves a free slot and returns the presigned form to upload into it.
+func (s *UploadService) CreateIntent(ctx context.Context, ownerUserID pgtype.UUID, params IntentParams) (*Intent, error) {
+	expired, err := s.db.DeleteExpiredUploadIntentsForOwner(ctx, ownerUserID)
+	if err != nil {
+		return nil, fmt.Errorf("delete expired upload intents: %w", err)
+	}
+	// Their forms have lapsed, so nothing new can land on these keys.
+	if len(expired) > 0 {
+		if err := s.resumeBucket.DeleteObjects(ctx, expired); err != nil {
+			s.log.Warn("Failed to delete expired uploads", zap.Strings("keys", expired), zap.Error(err))
+		}
+	}
+
+	slot, err := s.db.FindFreeSlotForOwner(ctx, ownerUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoFreeSlot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find free slot: %w", err)
+	}
+
+	resumeID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	key := s.resumeBucket.UploadKey(ownerUserID.String(), resumeID.String())
+
+	post, expiresAt, err := s.resumeBucket.PresignedUpload(ctx, key, s.ttl)
+	if errors.Is(err, spaces.ErrPresignUnsupported) {
+		return nil, ErrUploadsDisabled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("presign upload: %w", err)
+	}
+
+	_, err = s.db.CreateUploadIntent(ctx, sqlc.CreateUploadIntentParams{
+		ID:            resumeID,
+		OwnerUserID:   ownerUserID,
+		Slot:          slot,
+		Name:          params.Name,
+		Industry:      params.Industry,
+		YoeBucket:     params.YoeBucket,
+		HideCompanies: params.HideCompanies,
+		ObjectKey:     key,
+		ExpiresAt:     pgtype.Timestamptz{Time: expiresAt.Add(finalizeWindow), Valid: true},
+	})
+	// A concurrent intent took the same slot.
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return nil, ErrNoFreeSlot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create upload intent: %w", err)
+	}
+
+	return &Intent{ResumeID: resumeID, Slot: slot, Upload: post, ExpiresAt: expiresAt}, nil
+}
+
+// Finalize validates the uploaded object and turns the intent into a resume in the
+// reserved slot with its render queued. Rejected files are deleted and the slot is
+// released.
+func (s *UploadService) Finalize(ctx context.Context, resumeID, ownerUserID pgtype.UUID) (*sqlc.AppResume, error) {
+	intent, err := s.db.GetUploadIntentForOwner(ctx, sqlc.GetUploadIntentForOwnerParams{ID: resumeID, OwnerUserID: ownerUserID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIntentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get upload intent: %w", err)
+	}
+	if !intent.ExpiresAt.Time.After(time.Now()) {
+		return nil, ErrIntentNotFound
+	}
+
+	data, contentType, err := s.readUpload(ctx, intent.ObjectKey)
+	if err != nil {
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			s.reject(ctx, intent)
+		}
+		return nil, err
+	}
+
+	pdfMetadata, err := utils.ValidateResumePDF(data, contentType)
+	if err != nil {
+		s.reject(ctx, intent)
+		return nil, &RejectedError{Reason: err}
+	}
+
+	// Content-addressed like every other upload. If the commit below fails this copy
+	// is left for the orphan sweep.
+	key := s.resumeBucket.ContentKey(ownerUserID.String(), resumeID.String(), pdfMetadata.ContentHash)
+	if err := s.resumeBucket.PutPDF(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("store resume: %w", err)
+	}
+
+	resume, err := s.createResume(ctx, intent, key, pdfMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.resumeBucket.DeleteObjects(ctx, []string{intent.ObjectKey}); err != nil {
+		s.log.Warn("Failed to delete staged upload", zap.String("key", intent.ObjectKey), zap.Error(err))
+	}
+
+	return resume, nil
+}
+
+// readUpload fetches the staged object, refusing anything over the size limit before
+// reading it.
+func (s *UploadService) readUpload(ctx context.Context, key string) ([]byte, string, error) {
+	obj, err := s.resumeBucket.HeadObject(ctx, key)
+	if errors.Is(err, spaces.ErrObjectNotFound) {
+		return nil, "", ErrUploadMissing
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("head upload: %w", err)
+	}
+	if obj.Size > utils.MAX_RESUME_FILE_SIZE {
+		return nil, "", &RejectedError{Reason: errors.New("file is too large")}
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.resumeBucket.StreamFileToWriter(ctx, key, &buf); err != nil {
+		return nil, "", fmt.Errorf("read upload: %w", err)
+	}
+	return buf.Bytes(), obj.ContentType, nil
+}
+
+func (s *UploadService) createResume(ctx context.Context, intent sqlc.AppUploadIntent, key string, pdfMetadata *utils.PDFMetadata) (*sqlc.AppResume, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	// A concurrent finalize of the same intent waits here and then finds it gone.
+	intent, err = q.LockUploadIntentForOwner(ctx, sqlc.LockUploadIntentForOwnerParams{ID: intent.ID, OwnerUserID: intent.OwnerUserID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIntentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock upload intent: %w", err)
+	}
+
+	resume, err := q.CreateResumeWithSlot(ctx, sqlc.CreateResumeWithSlotParams{
+		OwnerUserID:    intent.OwnerUserID,
+		Slot:           intent.Slot,
+		Name:           intent.Name,
+		Industry:       intent.Industry,
+		YoeBucket:      intent.YoeBucket,
+		PdfStorageKey:  pgtype.Text{String: key, Valid: true},
+		PdfSizeBytes:   pdfMetadata.SizeBytes,
+		Column8:        pdfMetadata.MimeType,
+		ImageKeyPrefix: pgtype.Text{String: "", Valid: true},
+		Column10:       pdfMetadata.PageCount,
+		Column11:       false,
+		HideCompanies:  intent.HideCompanies,
+		ID:             intent.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create resume: %w", err)
+	}
+
+	// Version 1 is recorded up front, as with multipart uploads.
+	if err := q.SnapshotCurrentResumeVersion(ctx, resume.ID); err != nil {
+		return nil, fmt.Errorf("snapshot version: %w", err)
+	}
+
+	if err := q.DeleteUploadIntent(ctx, intent.ID); err != nil {
+		return nil, fmt.Errorf("delete upload intent: %w", err)
+	}
+
+	// Rendering and redaction happen in the background; image_ready flips once the preview is up.
+	if err := s.processingService.EnqueueRenderTx(ctx, q, resume.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return &resume, nil
+}
+
+// reject removes a file that failed validation and releases its slot. Failures are
+// only logged: the orphan sweep and intent expiry clean up eventually.
+func (s *UploadService) reject(ctx context.Context, intent sqlc.AppUploadIntent) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.resumeBucket.DeleteObjects(ctx, []string{intent.ObjectKey}); err != nil {
+		s.log.Error("Failed to delete rejected upload", zap.String("key", intent.ObjectKey), zap.Error(err))
+	}
+	if err := s.db.DeleteUploadIntent(ctx, intent.ID); err != nil {
+		s.log.Error("Failed to delete upload intent", zap.String("intent_id", intent.ID.String()), zap.Error(err))
+	}
+}
@@ -0,0 +1,53 @@
+package version
+
+import (
+	"math"
+
+	"main/utils"
+)
+
+// Rating every resume starts from.
+const baseElo = 1000
+
+// EloCarryPolicy decides the rating and battle count a resume starts a new version with.
+type EloCarryPolicy interface {
+	NextRating(elo, battles int32) (int32, int32)
+}
+
+// ResetPolicy treats a new version as a brand new resume.
+type ResetPolicy struct{}
+
+func (ResetPolicy) NextRating(elo, battles int32) (int32, int32) {
+	return baseElo, 0
+}
+
+// CarryPolicy keeps the rating and battle count unchanged.
+type CarryPolicy struct{}
+
+func (CarryPolicy) NextRating(elo, battles int32) (int32, int32) {
+	return elo, battles
+}
+
+// DecayPolicy keeps Retain of the distance from the base rating and restarts the
+// battle count, so the new version settles quickly under provisional K-factors.
+type DecayPolicy struct {
+	Retain float64
+}
+
+func (p DecayPolicy) NextRating(elo, battles int32) (int32, int32) {
+	return baseElo + int32(math.Round(float64(elo-baseElo)*p.Retain)), 0
+}
+
+func NewEloCarryPolicy(cfg *utils.VersionConfig) EloCarryPolicy {
+	if cfg == nil {
+		panic("cfg must be non-nil")
+	}
+	switch cfg.EloPolicy {
+	case "reset":
+		return ResetPolicy{}
+	case "carry":
+		return CarryPolicy{}
+	default:
+		return DecayPolicy{Retain: cfg.DecayRetain}
+	}
+}
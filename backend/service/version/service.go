@@ -0,0 +1,257 @@
+package version
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	sqlc "main/db/sqlc"
+	"main/service/processing"
+	"main/service/spaces"
+	"main/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Resume versions: new revisions uploaded into an existing slot, each keeping its
+// own PDF and renditions so the owner can roll back.
+
+var (
+	ErrResumeNotFound  = errors.New("resume not found")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrResumeInMatch   = errors.New("resume is in an open match")
+	ErrAlreadyCurrent  = errors.New("version is already current")
+)
+
+// Processing states set on the resume when a version goes live.
+const (
+	stateQueued = "queued"
+	stateReady  = "ready"
+)
+
+type VersionService struct {
+	pool              *pgxpool.Pool
+	db                *sqlc.Queries
+	resumeBucket      *spaces.ResumeBucket
+	processingService *processing.ProcessingService
+	policy            EloCarryPolicy
+	log               *zap.Logger
+}
+
+func NewVersionService(pool *pgxpool.Pool, db *sqlc.Queries, resumeBucket *spaces.ResumeBucket, processingService *processing.ProcessingService, policy EloCarryPolicy, log *zap.Logger) *VersionService {
+	if pool == nil || db == nil || resumeBucket == nil || processingService == nil || policy == nil || log == nil {
+		panic("pool, db, resumeBucket, processingService, policy, and log must be non-nil")
+	}
+	return &VersionService{pool: pool, db: db, resumeBucket: resumeBucket, processingService: processingService, policy: policy, log: log}
+}
+
+// CreateVersion uploads a new revision into the resume's slot and makes it live with
+// a rating chosen by the carry-over policy. The preview is not ready until the render
+// job queued with it finishes.
+func (s *VersionService) CreateVersion(ctx context.Context, resumeID, ownerUserID pgtype.UUID, file *multipart.FileHeader, pdfMetadata *utils.PDFMetadata) (*sqlc.AppResume, error) {
+	// Check ownership before uploading so strangers can't write into the slot, but keep
+	// the upload out of the transaction so the row isn't locked while it runs.
+	resume, err := s.db.GetResumeByIDForOwner(ctx, sqlc.GetResumeByIDForOwnerParams{ID: resumeID, OwnerUserID: ownerUserID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrResumeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get resume: %w", err)
+	}
+	if resume.InFlight {
+		return nil, ErrResumeInMatch
+	}
+
+	// If anything below fails the object is left behind for the orphan sweep.
+	key := s.resumeBucket.ContentKey(resume.OwnerUserID.String(), resume.ID.String(), pdfMetadata.ContentHash)
+	if err := s.resumeBucket.UploadPDF(ctx, key, file); err != nil {
+		return nil, fmt.Errorf("upload version: %w", err)
+	}
+	storageKey := pgtype.Text{String: key, Valid: true}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	locked, err := s.lockResume(ctx, q, resumeID, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.SnapshotCurrentResumeVersion(ctx, locked.ID); err != nil {
+		return nil, fmt.Errorf("snapshot current version: %w", err)
+	}
+
+	next, err := q.NextResumeVersion(ctx, locked.ID)
+	if err != nil {
+		return nil, fmt.Errorf("next version: %w", err)
+	}
+
+	_, err = q.CreateResumeVersion(ctx, sqlc.CreateResumeVersionParams{
+		ResumeID:      locked.ID,
+		Version:       next,
+		PdfStorageKey: storageKey,
+		PdfSizeBytes:  pdfMetadata.SizeBytes,
+		PageCount:     pdfMetadata.PageCount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create version: %w", err)
+	}
+
+	elo, battles := s.policy.NextRating(locked.CurrentEloInt, locked.BattlesCount)
+	updated, err := q.SetResumeCurrentVersion(ctx, sqlc.SetResumeCurrentVersionParams{
+		Version:         next,
+		PdfStorageKey:   storageKey,
+		PdfSizeBytes:    pdfMetadata.SizeBytes,
+		PageCount:       pdfMetadata.PageCount,
+		ImageReady:      false,
+		ProcessingState: stateQueued,
+		CurrentEloInt:   elo,
+		BattlesCount:    battles,
+		ID:              locked.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("set current version: %w", err)
+	}
+
+	// Queued with the state change, so the resume can't be left out of matchmaking
+	// with nothing to render it.
+	if err := s.processingService.EnqueueRenderTx(ctx, q, locked.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.log.Info("Created resume version",
+		zap.String("resume_id", locked.ID.String()),
+		zap.Int32("version", next),
+		zap.Int32("elo_before", locked.CurrentEloInt),
+		zap.Int32("elo_after", elo),
+	)
+
+	return &updated, nil
+}
+
+// List returns every version of one of the owner's resumes, newest first, along
+// with the number of the version that is live.
+func (s *VersionService) List(ctx context.Context, resumeID, ownerUserID pgtype.UUID) ([]sqlc.AppResumeVersion, int32, error) {
+	resume, err := s.db.GetResumeByIDForOwner(ctx, sqlc.GetResumeByIDForOwnerParams{ID: resumeID, OwnerUserID: ownerUserID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, 0, ErrResumeNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("get resume: %w", err)
+	}
+
+	versions, err := s.db.ListResumeVersions(ctx, resumeID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list versions: %w", err)
+	}
+	return versions, resume.CurrentVersion, nil
+}
+
+// Rollback makes an earlier version live again, restoring the rating it had when it
+// was superseded. Returns whether the version has no renditions and a render was queued.
+func (s *VersionService) Rollback(ctx context.Context, resumeID, ownerUserID pgtype.UUID, target int32) (*sqlc.AppResume, bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	q := s.db.WithTx(tx)
+
+	resume, err := s.lockResume(ctx, q, resumeID, ownerUserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if resume.CurrentVersion == target {
+		return nil, false, ErrAlreadyCurrent
+	}
+
+	v, err := q.GetResumeVersion(ctx, sqlc.GetResumeVersionParams{ResumeID: resume.ID, Version: target})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get version: %w", err)
+	}
+
+	if err := q.SnapshotCurrentResumeVersion(ctx, resume.ID); err != nil {
+		return nil, false, fmt.Errorf("snapshot current version: %w", err)
+	}
+
+	elo, battles := int32(baseElo), int32(0)
+	if v.FinalEloInt.Valid {
+		elo, battles = v.FinalEloInt.Int32, v.FinalBattlesCount.Int32
+	}
+	// Renditions masked under another hide_companies setting would leak or hide
+	// employer names, so they are re-rendered like missing ones.
+	needsRender := !v.ImageKeyPrefix.Valid || v.ImageKeyPrefix.String == "" ||
+		!v.RenderedHideCompanies.Valid || v.RenderedHideCompanies.Bool != resume.HideCompanies
+	state := stateReady
+	if needsRender {
+		state = stateQueued
+	}
+
+	updated, err := q.SetResumeCurrentVersion(ctx, sqlc.SetResumeCurrentVersionParams{
+		Version:         v.Version,
+		PdfStorageKey:   v.PdfStorageKey,
+		PdfSizeBytes:    v.PdfSizeBytes,
+		PageCount:       v.PageCount,
+		ImageKeyPrefix:  v.ImageKeyPrefix,
+		ImageManifest:   v.ImageManifest,
+		ImageReady:      !needsRender,
+		ProcessingState: state,
+		CurrentEloInt:   elo,
+		BattlesCount:    battles,
+		ID:              resume.ID,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("set current version: %w", err)
+	}
+
+	if needsRender {
+		if err := s.processingService.EnqueueRenderTx(ctx, q, resume.ID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.log.Info("Rolled back resume version",
+		zap.String("resume_id", resume.ID.String()),
+		zap.Int32("from_version", resume.CurrentVersion),
+		zap.Int32("to_version", v.Version),
+	)
+
+	return &updated, needsRender, nil
+}
+
+// lockResume locks the owner's resume row and refuses while it is in an open match,
+// since swapping files or rating mid-battle would skew the vote.
+func (s *VersionService) lockResume(ctx context.Context, q *sqlc.Queries, resumeID, ownerUserID pgtype.UUID) (*sqlc.AppResume, error) {
+	resume, err := q.GetResumeByIDForOwnerForUpdate(ctx, sqlc.GetResumeByIDForOwnerForUpdateParams{ID: resumeID, OwnerUserID: ownerUserID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrResumeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock resume: %w", err)
+	}
+	if resume.InFlight {
+		return nil, ErrResumeInMatch
+	}
+	return &resume, nil
+}
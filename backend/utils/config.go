@@ -1,44 +1,204 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type BucketConfig struct {
+	// "s3", "disk" or "memory". Endpoint, region and keys are only needed for s3.
+	Driver         string
 	BucketEndpoint string
 	BucketRegion   string
+	// Root directory of the disk driver; each bucket is a subdirectory.
+	DiskRoot string
 }
 
 type ResumeConfig struct {
 	BucketName      string
 	AccessKeyID     string
 	AccessKeySecret string
+	// Lifetime of presigned download links handed to owners.
+	PresignTTL time.Duration
+	// Lifetime of presigned direct-upload forms.
+	UploadTTL time.Duration
 }
 
 type WebpConfig struct {
 	BucketName      string
 	AccessKeyID     string
 	AccessKeySecret string
+	// Public base the webp keys are appended to, normally the CDN host.
+	CDNBaseURL string
 }
 
 type SupabaseConfig struct {
 	URL      string
 	Key      string
+	// HS256 secret. Optional once JWKSURL is set; tokens signed with it are still accepted.
 	JWTSecret string
+	// Asymmetric (RS256/ES256) signing keys. Empty disables them.
+	JWKSURL             string
+	JWKSRefreshInterval time.Duration
+	// Checked only when set.
+	JWTIssuer   string
+	JWTAudience string
+	// Reject tokens without an exp claim.
+	JWTRequireExpiry bool
+	// Clock skew allowed on exp, nbf and iat.
+	JWTLeeway time.Duration
+}
+
+// EloConfig holds the K-factor tiers. A resume uses ProvisionalK for its first
+// ProvisionalBattles battles, DefaultK until VeteranBattles, VeteranK until
+// EliteBattles and EliteK after that.
+type EloConfig struct {
+	ProvisionalK       int32
+	ProvisionalBattles int32
+	DefaultK           int32
+	VeteranK           int32
+	VeteranBattles     int32
+	EliteK             int32
+	EliteBattles       int32
+}
+
+type MatchConfig struct {
+	// Matches left in 'created' longer than this are cancelled by the reaper.
+	TTL            time.Duration
+	ReaperInterval time.Duration
+}
+
+type LeaderboardConfig struct {
+	// "live" queries app.resumes directly; "materialized" reads app.leaderboard.
+	Source              string
+	RefreshInterval     time.Duration
+	RefreshAfterMatches int32
+}
+
+// ProcessingConfig sizes the background worker pool that renders uploaded resumes.
+type ProcessingConfig struct {
+	Workers      int32
+	PollInterval time.Duration
+	MaxAttempts  int32
+	// Retries wait RetryBaseDelay * 2^(attempt-1), capped at RetryMaxDelay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Running jobs not finished after this long are assumed abandoned and requeued.
+	StuckAfter time.Duration
+}
+
+// VersionConfig decides what happens to a resume's rating when a new version is uploaded:
+// "reset" starts over at 1000, "carry" keeps it, "decay" keeps DecayRetain of the
+// distance from 1000. Reset and decay also restart the battle count so the new
+// version gets provisional K-factors.
+type VersionConfig struct {
+	EloPolicy   string
+	DecayRetain float64
+}
+
+// DeletionConfig drives the retrier that finishes removing a deleted resume's objects.
+type DeletionConfig struct {
+	RetryInterval time.Duration
+	// Retries wait RetryBaseDelay * 2^(attempt-1), capped at RetryMaxDelay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// GCConfig drives the collector that removes bucket objects no resume references.
+type GCConfig struct {
+	// Run the collector on Interval from the server; it can also be run from cmd/gc.
+	Enabled  bool
+	Interval time.Duration
+	// Objects younger than this are kept, covering uploads whose row isn't written yet.
+	GracePeriod time.Duration
+	// Only report what would be deleted.
+	DryRun bool
+}
+
+// GuestConfig controls voting without an account. Guests get a backend-signed
+// session token; their votes move ratings by VoteWeight of the normal K.
+type GuestConfig struct {
+	Enabled    bool
+	Secret     string
+	SessionTTL time.Duration
+	// 0 records guest votes without changing ratings, 1 counts them fully.
+	VoteWeight float64
+	// Mark the session cookie Secure. Disable only for plain-http local development.
+	CookieSecure bool
+}
+
+// RateLimit is a token bucket: PerMinute tokens refill each minute, up to Burst.
+type RateLimit struct {
+	PerMinute float64
+	Burst     int32
+}
+
+// RateLimitConfig sets per-route-group request limits. Requests are counted per
+// signed-in user, or per client IP for everyone else.
+type RateLimitConfig struct {
+	Enabled bool
+	// IPs or CIDRs of the reverse proxies whose X-Forwarded-For is believed when
+	// working out the client IP. Empty trusts none and uses the connection's address.
+	TrustedProxies []string
+	// Routes not in one of the groups below.
+	Default     RateLimit
+	Upload      RateLimit
+	Matchmaking RateLimit
+	Vote        RateLimit
+}
+
+type ImageConfig struct {
+	// Widths, in pixels, each page is rendered at, ascending.
+	Widths []int
+}
+
+// AnonymizeConfig controls what is blacked out of the public resume previews.
+type AnonymizeConfig struct {
+	// Name -> regular expression, applied on top of the built-in email/phone/URL patterns.
+	ExtraPatterns map[string]string
+	RedactName    bool
+	// Added to the built-in employer dictionary used when a resume hides company names.
+	ExtraCompanies []string
 }
 
 type Config struct {
-	Resume   *ResumeConfig
-	Webp     *WebpConfig
-	Bucket   *BucketConfig
-	Supabase *SupabaseConfig
+	Resume      *ResumeConfig
+	Webp        *WebpConfig
+	Bucket      *BucketConfig
+	Supabase    *SupabaseConfig
+	Elo         *EloConfig
+	Match       *MatchConfig
+	Leaderboard *LeaderboardConfig
+	Anonymize   *AnonymizeConfig
+	Processing  *ProcessingConfig
+	Image       *ImageConfig
+	Version     *VersionConfig
+	Deletion    *DeletionConfig
+	Guest       *GuestConfig
+	RateLimit   *RateLimitConfig
+	GC          *GCConfig
 }
 
 func LoadConfig() (*Config, error) {
 	bucketConfig := &BucketConfig{
+		Driver:         os.Getenv("STORAGE_DRIVER"),
 		BucketEndpoint: os.Getenv("BUCKET_ENDPOINT"),
 		BucketRegion:   os.Getenv("BUCKET_REGION"),
+		DiskRoot:       os.Getenv("STORAGE_DISK_ROOT"),
+	}
+	if bucketConfig.Driver == "" {
+		bucketConfig.Driver = "s3"
+	}
+	if bucketConfig.DiskRoot == "" {
+		bucketConfig.DiskRoot = "data/storage"
 	}
 
 	resumeConfig := &ResumeConfig{
@@ -53,17 +213,273 @@ func LoadConfig() (*Config, error) {
 		AccessKeySecret: os.Getenv("CRUD_WEBP_ACCESS_KEY_SECRET"),
 	}
 
+	presignTTL, err := getEnvDuration("RESUME_PRESIGN_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	resumeConfig.PresignTTL = presignTTL
+
+	uploadTTL, err := getEnvDuration("RESUME_UPLOAD_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	resumeConfig.UploadTTL = uploadTTL
+
+	webpConfig.CDNBaseURL = os.Getenv("WEBP_CDN_BASE_URL")
+	if webpConfig.CDNBaseURL == "" {
+		if bucketConfig.Driver == "s3" {
+			// Path-style origin URL; slower than the CDN but always correct.
+			webpConfig.CDNBaseURL = strings.TrimRight(bucketConfig.BucketEndpoint, "/") + "/" + webpConfig.BucketName
+		} else {
+			webpConfig.CDNBaseURL = "http://localhost:8080/api/media"
+		}
+	}
+
 	supabaseConfig := &SupabaseConfig{
-		URL:      os.Getenv("SUPABASE_URL"),
-		Key:      os.Getenv("SUPABASE_KEY"),
-		JWTSecret: os.Getenv("SUPABASE_JWT_SECRET"),
+		URL:         os.Getenv("SUPABASE_URL"),
+		Key:         os.Getenv("SUPABASE_KEY"),
+		JWTSecret:   os.Getenv("SUPABASE_JWT_SECRET"),
+		JWKSURL:     os.Getenv("SUPABASE_JWKS_URL"),
+		JWTIssuer:   os.Getenv("SUPABASE_JWT_ISSUER"),
+		JWTAudience: os.Getenv("SUPABASE_JWT_AUDIENCE"),
+	}
+	supabaseConfig.JWKSRefreshInterval, err = getEnvDuration("SUPABASE_JWKS_REFRESH_INTERVAL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	supabaseConfig.JWTLeeway, err = getEnvDuration("SUPABASE_JWT_LEEWAY", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	supabaseConfig.JWTRequireExpiry, err = getEnvBool("SUPABASE_JWT_REQUIRE_EXP", true)
+	if err != nil {
+		return nil, err
+	}
+
+	eloConfig := &EloConfig{}
+	for _, v := range []struct {
+		dst      *int32
+		key      string
+		fallback int32
+	}{
+		{&eloConfig.ProvisionalK, "ELO_K_PROVISIONAL", 40},
+		{&eloConfig.ProvisionalBattles, "ELO_PROVISIONAL_BATTLES", 10},
+		{&eloConfig.DefaultK, "ELO_K_DEFAULT", 32},
+		{&eloConfig.VeteranK, "ELO_K_VETERAN", 24},
+		{&eloConfig.VeteranBattles, "ELO_VETERAN_BATTLES", 50},
+		{&eloConfig.EliteK, "ELO_K_ELITE", 16},
+		{&eloConfig.EliteBattles, "ELO_ELITE_BATTLES", 100},
+	} {
+		n, err := getEnvInt32(v.key, v.fallback)
+		if err != nil {
+			return nil, err
+		}
+		*v.dst = n
+	}
+
+	matchTTL, err := getEnvDuration("MATCH_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	reaperInterval, err := getEnvDuration("MATCH_REAPER_INTERVAL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	matchConfig := &MatchConfig{
+		TTL:            matchTTL,
+		ReaperInterval: reaperInterval,
+	}
+
+	leaderboardSource := os.Getenv("LEADERBOARD_SOURCE")
+	if leaderboardSource == "" {
+		leaderboardSource = "live"
+	}
+	refreshInterval, err := getEnvDuration("LEADERBOARD_REFRESH_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	refreshAfterMatches, err := getEnvInt32("LEADERBOARD_REFRESH_AFTER_MATCHES", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboardConfig := &LeaderboardConfig{
+		Source:              leaderboardSource,
+		RefreshInterval:     refreshInterval,
+		RefreshAfterMatches: refreshAfterMatches,
+	}
+
+	anonymizeConfig := &AnonymizeConfig{ExtraPatterns: map[string]string{}}
+	if raw := os.Getenv("PII_EXTRA_PATTERNS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &anonymizeConfig.ExtraPatterns); err != nil {
+			return nil, fmt.Errorf("PII_EXTRA_PATTERNS must be a JSON object of name to regex: %w", err)
+		}
+	}
+	anonymizeConfig.RedactName, err = getEnvBool("PII_REDACT_NAME", true)
+	if err != nil {
+		return nil, err
+	}
+	if raw := os.Getenv("PII_EXTRA_COMPANIES"); raw != "" {
+		anonymizeConfig.ExtraCompanies = strings.Split(raw, ",")
+	}
+
+	processingConfig := &ProcessingConfig{}
+	for _, v := range []struct {
+		dst      *int32
+		key      string
+		fallback int32
+	}{
+		{&processingConfig.Workers, "PROCESSING_WORKERS", 4},
+		{&processingConfig.MaxAttempts, "PROCESSING_MAX_ATTEMPTS", 5},
+	} {
+		n, err := getEnvInt32(v.key, v.fallback)
+		if err != nil {
+			return nil, err
+		}
+		*v.dst = n
+	}
+	for _, v := range []struct {
+		dst      *time.Duration
+		key      string
+		fallback time.Duration
+	}{
+		{&processingConfig.PollInterval, "PROCESSING_POLL_INTERVAL", time.Second},
+		{&processingConfig.RetryBaseDelay, "PROCESSING_RETRY_BASE_DELAY", 10 * time.Second},
+		{&processingConfig.RetryMaxDelay, "PROCESSING_RETRY_MAX_DELAY", 10 * time.Minute},
+		{&processingConfig.StuckAfter, "PROCESSING_STUCK_AFTER", 10 * time.Minute},
+	} {
+		d, err := getEnvDuration(v.key, v.fallback)
+		if err != nil {
+			return nil, err
+		}
+		*v.dst = d
+	}
+
+	imageConfig := &ImageConfig{Widths: []int{400, 800, 1600}}
+	if raw := os.Getenv("IMAGE_WIDTHS"); raw != "" {
+		imageConfig.Widths = nil
+		for _, part := range strings.Split(raw, ",") {
+			w, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("IMAGE_WIDTHS must be a comma separated list of integers: %w", err)
+			}
+			imageConfig.Widths = append(imageConfig.Widths, w)
+		}
+		sort.Ints(imageConfig.Widths)
+		// A repeated width would render and upload the same rendition twice.
+		imageConfig.Widths = slices.Compact(imageConfig.Widths)
+	}
+
+	versionConfig := &VersionConfig{EloPolicy: os.Getenv("RESUME_VERSION_ELO_POLICY")}
+	if versionConfig.EloPolicy == "" {
+		versionConfig.EloPolicy = "decay"
+	}
+	versionConfig.DecayRetain, err = getEnvFloat("RESUME_VERSION_ELO_RETAIN", 0.5)
+	if err != nil {
+		return nil, err
+	}
+
+	deletionConfig := &DeletionConfig{}
+	for _, v := range []struct {
+		dst      *time.Duration
+		key      string
+		fallback time.Duration
+	}{
+		{&deletionConfig.RetryInterval, "DELETION_RETRY_INTERVAL", time.Minute},
+		{&deletionConfig.RetryBaseDelay, "DELETION_RETRY_BASE_DELAY", 30 * time.Second},
+		{&deletionConfig.RetryMaxDelay, "DELETION_RETRY_MAX_DELAY", time.Hour},
+	} {
+		d, err := getEnvDuration(v.key, v.fallback)
+		if err != nil {
+			return nil, err
+		}
+		*v.dst = d
+	}
+
+	gcConfig := &GCConfig{}
+	gcConfig.Enabled, err = getEnvBool("GC_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	gcConfig.DryRun, err = getEnvBool("GC_DRY_RUN", false)
+	if err != nil {
+		return nil, err
+	}
+	gcConfig.Interval, err = getEnvDuration("GC_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	gcConfig.GracePeriod, err = getEnvDuration("GC_GRACE_PERIOD", 72*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	guestConfig := &GuestConfig{Secret: os.Getenv("GUEST_SESSION_SECRET")}
+	guestConfig.Enabled, err = getEnvBool("GUEST_VOTING_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	guestConfig.SessionTTL, err = getEnvDuration("GUEST_SESSION_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	guestConfig.VoteWeight, err = getEnvFloat("GUEST_VOTE_WEIGHT", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	guestConfig.CookieSecure, err = getEnvBool("GUEST_COOKIE_SECURE", true)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitConfig := &RateLimitConfig{}
+	rateLimitConfig.Enabled, err = getEnvBool("RATE_LIMIT_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	if raw := os.Getenv("TRUSTED_PROXIES"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			rateLimitConfig.TrustedProxies = append(rateLimitConfig.TrustedProxies, strings.TrimSpace(part))
+		}
+	}
+	for _, v := range []struct {
+		dst      *RateLimit
+		key      string
+		fallback RateLimit
+	}{
+		{&rateLimitConfig.Default, "RATE_LIMIT_DEFAULT", RateLimit{PerMinute: 120, Burst: 60}},
+		{&rateLimitConfig.Upload, "RATE_LIMIT_UPLOAD", RateLimit{PerMinute: 10, Burst: 5}},
+		{&rateLimitConfig.Matchmaking, "RATE_LIMIT_MATCHMAKING", RateLimit{PerMinute: 60, Burst: 20}},
+		{&rateLimitConfig.Vote, "RATE_LIMIT_VOTE", RateLimit{PerMinute: 60, Burst: 20}},
+	} {
+		perMinute, err := getEnvFloat(v.key+"_PER_MINUTE", v.fallback.PerMinute)
+		if err != nil {
+			return nil, err
+		}
+		burst, err := getEnvInt32(v.key+"_BURST", v.fallback.Burst)
+		if err != nil {
+			return nil, err
+		}
+		*v.dst = RateLimit{PerMinute: perMinute, Burst: burst}
 	}
 
 	config := &Config{
-		Resume:   resumeConfig,
-		Webp:     webpConfig,
-		Bucket:   bucketConfig,
-		Supabase: supabaseConfig,
+		Resume:      resumeConfig,
+		Webp:        webpConfig,
+		Bucket:      bucketConfig,
+		Supabase:    supabaseConfig,
+		Elo:         eloConfig,
+		Match:       matchConfig,
+		Leaderboard: leaderboardConfig,
+		Anonymize:   anonymizeConfig,
+		Processing:  processingConfig,
+		Image:       imageConfig,
+		Version:     versionConfig,
+		Deletion:    deletionConfig,
+		GC:          gcConfig,
+		Guest:       guestConfig,
+		RateLimit:   rateLimitConfig,
 	}
 
 	// Validate required environment variables
@@ -75,10 +491,151 @@ func LoadConfig() (*Config, error) {
 }
 
 func validateConfig(config *Config) error {
-	// Validate Resume bucket config
+	// Validate bucket names; the disk driver uses them as directory names.
 	if config.Resume.BucketName == "" {
 		return fmt.Errorf("CRUD_RESUMES_BUCKET environment variable is required")
 	}
+	if config.Webp.BucketName == "" {
+		return fmt.Errorf("CRUD_WEBPS_BUCKET environment variable is required")
+	}
+
+	switch config.Bucket.Driver {
+	case "s3":
+		if err := validateS3Config(config); err != nil {
+			return err
+		}
+	case "disk", "memory":
+	default:
+		return fmt.Errorf("STORAGE_DRIVER must be one of s3, disk or memory")
+	}
+
+	// S3 caps presigned URLs at 7 days.
+	if config.Resume.PresignTTL < time.Second || config.Resume.PresignTTL > 7*24*time.Hour {
+		return fmt.Errorf("RESUME_PRESIGN_TTL must be between 1s and 168h")
+	}
+	if config.Resume.UploadTTL < time.Minute || config.Resume.UploadTTL > 7*24*time.Hour {
+		return fmt.Errorf("RESUME_UPLOAD_TTL must be between 1m and 168h")
+	}
+
+	// Validate Supabase config
+	if config.Supabase.JWTSecret == "" && config.Supabase.JWKSURL == "" {
+		return fmt.Errorf("SUPABASE_JWT_SECRET or SUPABASE_JWKS_URL environment variable is required")
+	}
+	if config.Supabase.JWKSURL != "" && config.Supabase.JWKSRefreshInterval < time.Minute {
+		return fmt.Errorf("SUPABASE_JWKS_REFRESH_INTERVAL must be at least 1m")
+	}
+	if config.Supabase.JWTLeeway < 0 {
+		return fmt.Errorf("SUPABASE_JWT_LEEWAY must not be negative")
+	}
+
+	// Validate Elo config
+	for _, k := range []int32{config.Elo.ProvisionalK, config.Elo.DefaultK, config.Elo.VeteranK, config.Elo.EliteK} {
+		if k <= 0 {
+			return fmt.Errorf("ELO_K_* values must be positive")
+		}
+	}
+	if config.Elo.ProvisionalBattles < 0 || config.Elo.ProvisionalBattles > config.Elo.VeteranBattles || config.Elo.VeteranBattles > config.Elo.EliteBattles {
+		return fmt.Errorf("ELO_*_BATTLES thresholds must satisfy 0 <= provisional <= veteran <= elite")
+	}
+
+	// Validate match config
+	if config.Match.TTL <= 0 || config.Match.ReaperInterval <= 0 {
+		return fmt.Errorf("MATCH_TTL and MATCH_REAPER_INTERVAL must be positive")
+	}
+
+	// Validate leaderboard config
+	if config.Leaderboard.Source != "live" && config.Leaderboard.Source != "materialized" {
+		return fmt.Errorf("LEADERBOARD_SOURCE must be either live or materialized")
+	}
+	if config.Leaderboard.RefreshInterval <= 0 || config.Leaderboard.RefreshAfterMatches < 0 {
+		return fmt.Errorf("LEADERBOARD_REFRESH_INTERVAL must be positive and LEADERBOARD_REFRESH_AFTER_MATCHES non-negative")
+	}
+
+	// Validate anonymize config
+	for name, expr := range config.Anonymize.ExtraPatterns {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("PII_EXTRA_PATTERNS entry %q is not a valid regex: %w", name, err)
+		}
+	}
+
+	// Validate processing config
+	if config.Processing.Workers <= 0 || config.Processing.MaxAttempts <= 0 {
+		return fmt.Errorf("PROCESSING_WORKERS and PROCESSING_MAX_ATTEMPTS must be positive")
+	}
+	if config.Processing.PollInterval <= 0 || config.Processing.RetryBaseDelay <= 0 ||
+		config.Processing.RetryMaxDelay < config.Processing.RetryBaseDelay || config.Processing.StuckAfter <= 0 {
+		return fmt.Errorf("PROCESSING_* durations must be positive and PROCESSING_RETRY_MAX_DELAY >= PROCESSING_RETRY_BASE_DELAY")
+	}
+
+	// Validate image config. Pages are rendered at 300 DPI (2550px for US Letter).
+	if len(config.Image.Widths) == 0 {
+		return fmt.Errorf("IMAGE_WIDTHS must list at least one width")
+	}
+	for _, w := range config.Image.Widths {
+		if w <= 0 || w > 2550 {
+			return fmt.Errorf("IMAGE_WIDTHS values must be between 1 and 2550")
+		}
+	}
+
+	// Validate version config
+	switch config.Version.EloPolicy {
+	case "reset", "carry", "decay":
+	default:
+		return fmt.Errorf("RESUME_VERSION_ELO_POLICY must be one of reset, carry or decay")
+	}
+	if config.Version.DecayRetain < 0 || config.Version.DecayRetain > 1 {
+		return fmt.Errorf("RESUME_VERSION_ELO_RETAIN must be between 0 and 1")
+	}
+
+	// Validate deletion config
+	if config.Deletion.RetryInterval <= 0 || config.Deletion.RetryBaseDelay <= 0 ||
+		config.Deletion.RetryMaxDelay < config.Deletion.RetryBaseDelay {
+		return fmt.Errorf("DELETION_* durations must be positive and DELETION_RETRY_MAX_DELAY >= DELETION_RETRY_BASE_DELAY")
+	}
+
+	// Validate GC config. A short grace period would race uploads still being written.
+	if config.GC.Interval <= 0 || config.GC.GracePeriod < time.Hour {
+		return fmt.Errorf("GC_INTERVAL must be positive and GC_GRACE_PERIOD at least 1h")
+	}
+
+	if config.Guest.Enabled {
+		// The secret signs guest tokens; a short one could be brute-forced offline.
+		if len(config.Guest.Secret) < 32 {
+			return fmt.Errorf("GUEST_SESSION_SECRET must be at least 32 characters when GUEST_VOTING_ENABLED is set")
+		}
+		if config.Guest.Secret == config.Supabase.JWTSecret {
+			return fmt.Errorf("GUEST_SESSION_SECRET must differ from SUPABASE_JWT_SECRET")
+		}
+		if config.Guest.SessionTTL <= 0 {
+			return fmt.Errorf("GUEST_SESSION_TTL must be positive")
+		}
+		if config.Guest.VoteWeight < 0 || config.Guest.VoteWeight > 1 {
+			return fmt.Errorf("GUEST_VOTE_WEIGHT must be between 0 and 1")
+		}
+	}
+
+	for name, limit := range map[string]RateLimit{
+		"DEFAULT":     config.RateLimit.Default,
+		"UPLOAD":      config.RateLimit.Upload,
+		"MATCHMAKING": config.RateLimit.Matchmaking,
+		"VOTE":        config.RateLimit.Vote,
+	} {
+		if limit.PerMinute <= 0 || limit.Burst < 1 {
+			return fmt.Errorf("RATE_LIMIT_%s_PER_MINUTE must be positive and RATE_LIMIT_%s_BURST at least 1", name, name)
+		}
+	}
+	for _, proxy := range config.RateLimit.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("TRUSTED_PROXIES must be a comma separated list of IPs or CIDRs, got %q", proxy)
+		}
+	}
+
+	return nil
+}
+
+// validateS3Config checks the credentials and endpoint only the s3 driver needs.
+func validateS3Config(config *Config) error {
+	// Validate Resume bucket config
 	if config.Resume.AccessKeyID == "" {
 		return fmt.Errorf("CRUD_RESUMES_ACCESS_KEY_ID environment variable is required")
 	}
@@ -87,9 +644,6 @@ func validateConfig(config *Config) error {
 	}
 
 	// Validate Webp bucket config
-	if config.Webp.BucketName == "" {
-		return fmt.Errorf("CRUD_WEBPS_BUCKET environment variable is required")
-	}
 	if config.Webp.AccessKeyID == "" {
 		return fmt.Errorf("CRUD_WEBPS_ACCESS_KEY_ID environment variable is required")
 	}
@@ -105,10 +659,57 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("BUCKET_REGION environment variable is required")
 	}
 
-	// Validate Supabase config
-	if config.Supabase.JWTSecret == "" {
-		return fmt.Errorf("SUPABASE_JWT_SECRET environment variable is required")
+	return nil
+}
+
+// getEnvInt32 reads an integer environment variable, falling back when it is unset.
+func getEnvInt32(key string, fallback int32) (int32, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
 	}
+	return int32(n), nil
+}
 
-	return nil
-}
\ No newline at end of file
+// getEnvDuration reads a time.ParseDuration value (e.g. "90s"), falling back when it is unset.
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration: %w", key, err)
+	}
+	return d, nil
+}
+
+// getEnvBool reads a strconv.ParseBool value, falling back when it is unset.
+func getEnvBool(key string, fallback bool) (bool, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %w", key, err)
+	}
+	return b, nil
+}
+
+// getEnvFloat reads a float environment variable, falling back when it is unset.
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+	return f, nil
+}
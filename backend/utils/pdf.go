@@ -3,6 +3,8 @@ package utils
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +29,8 @@ type PDFMetadata struct {
 	StorageKey pgtype.Text
 	SizeBytes  pgtype.Int8
 	MimeType   string
+	// Hex SHA-256 of the file, used to build its storage key.
+	ContentHash string
 }
 
 // ValidateResumeFile checks that resume file is not too large and has a valid number of pages
@@ -35,11 +39,6 @@ func ValidateResumeFile(file *multipart.FileHeader) (*PDFMetadata, error) {
 		return nil, errors.New("file is too large")
 	}
 
-	// Check that the file is a PDF from the content type
-	if file.Header.Get("Content-Type") != "application/pdf" {
-		return nil, errors.New("file is not a PDF")
-	}
-
 	src, err := file.Open()
 	if err != nil {
 		return nil, fmt.Errorf("open upload: %w", err)
@@ -51,21 +50,32 @@ func ValidateResumeFile(file *multipart.FileHeader) (*PDFMetadata, error) {
 	if _, err := io.CopyN(&buf, src, MAX_RESUME_FILE_SIZE+1); err != nil && err != io.EOF {
 		return nil, fmt.Errorf("read upload: %w", err)
 	}
-	if int64(buf.Len()) > MAX_RESUME_FILE_SIZE {
+
+	return ValidateResumePDF(buf.Bytes(), file.Header.Get("Content-Type"))
+}
+
+// ValidateResumePDF runs the resume checks on bytes already in memory, e.g. an object
+// the browser uploaded straight to the bucket. contentType is what the client claimed.
+func ValidateResumePDF(data []byte, contentType string) (*PDFMetadata, error) {
+	if int64(len(data)) > MAX_RESUME_FILE_SIZE {
 		return nil, errors.New("file is too large")
 	}
 
+	// Check that the file is a PDF from the content type
+	if contentType != "application/pdf" {
+		return nil, errors.New("file is not a PDF")
+	}
+
 	// Double check that the file is a PDF since clients can lie about the content type
-	head := buf.Bytes()
-	if ct := http.DetectContentType(head); ct != "application/pdf" && ct != "application/octet-stream" {
+	if ct := http.DetectContentType(data); ct != "application/pdf" && ct != "application/octet-stream" {
 		return nil, errors.New("file must be a PDF")
 	}
 
-	if !bytes.HasPrefix(head, []byte("%PDF-")) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
 		return nil, errors.New("invalid PDF header")
 	}
 
-	pageCount, err := CountPDFPagesWithTimeout(head, PARSE_TIMEOUT)
+	pageCount, err := CountPDFPagesWithTimeout(data, PARSE_TIMEOUT)
 	if err != nil {
 		return nil, errors.New("could not read PDF (corrupted or unsupported)")
 	}
@@ -77,13 +87,20 @@ func ValidateResumeFile(file *multipart.FileHeader) (*PDFMetadata, error) {
 	}
 
 	return &PDFMetadata{
-		PageCount:  int16(pageCount),
-		StorageKey: pgtype.Text{String: "TODO", Valid: true},
-		SizeBytes:  pgtype.Int8{Int64: int64(file.Size), Valid: true},
-		MimeType:   file.Header.Get("Content-Type"),
+		PageCount:   int16(pageCount),
+		StorageKey:  pgtype.Text{String: "TODO", Valid: true},
+		SizeBytes:   pgtype.Int8{Int64: int64(len(data)), Valid: true},
+		MimeType:    contentType,
+		ContentHash: ContentHash(data),
 	}, nil
 }
 
+// ContentHash returns the hex SHA-256 of a file's bytes.
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 // MimeTypeForFilename returns the MIME type for a given filename
 func MimeTypeForFilename(name, fallback string) string {
 	// Minimal inference without importing extra deps.
@@ -10,6 +10,7 @@ import {
 } from "react";
 import { createClient, User } from "@supabase/supabase-js";
 import axiosInstance from "@/lib/axiosInstance";
+import { mergeGuestSession } from "@/lib/guestSession";
 
 const supabaseUrl = process.env.NEXT_PUBLIC_SUPABASE_URL;
 const publicAnonKey = process.env.NEXT_PUBLIC_SUPABASE_ANON_KEY;
@@ -77,6 +78,9 @@ export default function AuthProvider({
       setUser(setExtendedUser(user));
       setStatus(user ? "authenticated" : "unauthenticated");
       setAxiosAuthHeader(token);
+      if (user && token) {
+        void mergeGuestSession();
+      }
     },
     [setAxiosAuthHeader]
   );
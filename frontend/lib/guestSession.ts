@@ -0,0 +1,60 @@
+import axiosInstance from "@/lib/axiosInstance";
+
+// Guest voting: the backend issues a signed token that stands in for an account on
+// the voting routes. It is kept in localStorage because the API is on another origin.
+
+const STORAGE_KEY = "guest_session";
+const HEADER = "X-Guest-Session";
+
+interface StoredGuestSession {
+  token: string;
+  expires_at: string;
+}
+
+function load(): StoredGuestSession | null {
+  if (typeof window === "undefined") return null;
+  try {
+    const session = JSON.parse(
+      localStorage.getItem(STORAGE_KEY) ?? "null"
+    ) as StoredGuestSession | null;
+    if (!session || new Date(session.expires_at) <= new Date()) return null;
+    return session;
+  } catch {
+    return null;
+  }
+}
+
+function clear() {
+  localStorage.removeItem(STORAGE_KEY);
+  delete axiosInstance.defaults.headers.common[HEADER];
+}
+
+// Starts (or reuses) a guest session and sends it with every request.
+export async function ensureGuestSession(): Promise<void> {
+  let session = load();
+  if (!session) {
+    const response = await axiosInstance.post("/guest/session");
+    session = {
+      token: response.data.token,
+      expires_at: response.data.expires_at,
+    };
+    localStorage.setItem(STORAGE_KEY, JSON.stringify(session));
+  }
+  axiosInstance.defaults.headers.common[HEADER] = session.token;
+}
+
+// Hands the guest's votes to the signed-in account. Call once the Authorization
+// header is set; the guest session is dropped either way.
+export async function mergeGuestSession(): Promise<void> {
+  const session = load();
+  if (!session) return;
+  try {
+    await axiosInstance.post("/guest/merge", null, {
+      headers: { [HEADER]: session.token },
+    });
+  } catch (error) {
+    console.error("Error merging guest session:", error);
+  } finally {
+    clear();
+  }
+}
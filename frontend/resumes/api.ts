@@ -1,10 +1,20 @@
+import axios from "axios";
 import axiosInstance from "@/lib/axiosInstance";
-import { Resume } from "./types";
+import { ProcessingStatus, Resume, ResumeVersion } from "./types";
 
 interface UploadResumeResponse {
   message: string;
 }
 
+interface UploadIntentResponse {
+  resume_id: string;
+  slot: number;
+  upload: { url: string; fields: Record<string, string> };
+  expires_at: string;
+  max_size: number;
+  content_type: string;
+}
+
 interface RenameResumeRequest {
   resume_id: string;
   resume_name: string;
@@ -28,6 +38,10 @@ interface RenameResumeResponse {
   page_count: number;
   image_ready: boolean;
   slot: number;
+  hide_companies: boolean;
+  processing_state: string;
+  processing_error: string | null;
+  current_version: number;
 }
 
 class ResumeApi {
@@ -41,14 +55,25 @@ class ResumeApi {
     resumeName: string,
     userId: string,
     industry: string,
-    yoeBucket: string
+    yoeBucket: string,
+    hideCompanies = false
   ): Promise<UploadResumeResponse> {
+    const direct = await this.uploadDirect(
+      file,
+      resumeName,
+      industry,
+      yoeBucket,
+      hideCompanies
+    );
+    if (direct) return direct;
+
     const formData = new FormData();
     formData.append("file", file);
     formData.append("resume_name", resumeName);
     formData.append("user_id", userId);
     formData.append("industry", industry);
     formData.append("yoe_bucket", yoeBucket);
+    formData.append("hide_companies", String(hideCompanies));
 
     const response = await axiosInstance.post("/storage", formData, {
       headers: {
@@ -58,6 +83,47 @@ class ResumeApi {
     return response.data;
   }
 
+  // Uploads straight to the bucket through a presigned form. Resolves null when the
+  // backend's storage can't presign (501) so the caller falls back to multipart.
+  private async uploadDirect(
+    file: File,
+    resumeName: string,
+    industry: string,
+    yoeBucket: string,
+    hideCompanies: boolean
+  ): Promise<UploadResumeResponse | null> {
+    let intent: UploadIntentResponse;
+    try {
+      const response = await axiosInstance.post("/storage/upload-intent", {
+        resume_name: resumeName,
+        industry,
+        yoe_bucket: yoeBucket,
+        hide_companies: hideCompanies,
+      });
+      intent = response.data;
+    } catch (err) {
+      if (axios.isAxiosError(err) && err.response?.status === 501) return null;
+      throw err;
+    }
+
+    const form = new FormData();
+    for (const [name, value] of Object.entries(intent.upload.fields)) {
+      form.append(name, value);
+    }
+    // The file has to be the last field.
+    form.append("file", file);
+
+    const upload = await fetch(intent.upload.url, { method: "POST", body: form });
+    if (!upload.ok) {
+      throw new Error("Upload was rejected by storage");
+    }
+
+    const response = await axiosInstance.post(
+      `/storage/${intent.resume_id}/finalize`
+    );
+    return response.data;
+  }
+
   async renameResume(
     request: RenameResumeRequest
   ): Promise<RenameResumeResponse> {
@@ -68,17 +134,106 @@ class ResumeApi {
     return response.data;
   }
 
-  async deleteResume(
+  async updateAnonymization(
     resumeId: string,
-    imageKeyPrefix: string,
-    pdfStorageKey: string
+    hideCompanies: boolean
+  ): Promise<Resume> {
+    const response = await axiosInstance.put(
+      `/resume/${resumeId}/anonymization`,
+      { hide_companies: hideCompanies }
+    );
+    return response.data;
+  }
+
+  async getVersions(resumeId: string): Promise<ResumeVersion[]> {
+    const response = await axiosInstance.get(`/resume/${resumeId}/versions`);
+    return response.data.versions;
+  }
+
+  async uploadVersion(resumeId: string, file: File): Promise<Resume> {
+    const formData = new FormData();
+    formData.append("file", file);
+
+    const response = await axiosInstance.post(
+      `/resume/${resumeId}/versions`,
+      formData,
+      {
+        headers: {
+          "Content-Type": "multipart/form-data",
+        },
+      }
+    );
+    return response.data.resume;
+  }
+
+  async rollbackVersion(resumeId: string, version: number): Promise<Resume> {
+    const response = await axiosInstance.post(
+      `/resume/${resumeId}/versions/${version}/rollback`
+    );
+    return response.data.resume;
+  }
+
+  async deleteResume(resumeId: string): Promise<void> {
+    await axiosInstance.delete(`/resume/${resumeId}`);
+  }
+
+  async getStatus(resumeId: string): Promise<ProcessingStatus> {
+    const response = await axiosInstance.get(`/resume/${resumeId}/status`);
+    return response.data;
+  }
+
+  // EventSource can't send the Authorization header, so the stream is read with fetch.
+  // Resolves when the server ends the stream (the resume is ready or failed).
+  async watchStatus(
+    resumeId: string,
+    onStatus: (status: ProcessingStatus) => void,
+    signal: AbortSignal
   ): Promise<void> {
-    await axiosInstance.delete(`/resume/${resumeId}`, {
-      data: {
-        image_key_prefix: imageKeyPrefix,
-        pdf_storage_key: pdfStorageKey,
-      },
-    });
+    const response = await fetch(
+      `${axiosInstance.defaults.baseURL}/resume/${resumeId}/events`,
+      {
+        headers: {
+          Accept: "text/event-stream",
+          Authorization: String(
+            axiosInstance.defaults.headers.common["Authorization"] ?? ""
+          ),
+        },
+        signal,
+      }
+    );
+    if (!response.ok || !response.body) {
+      throw new Error(`Failed to watch resume status (${response.status})`);
+    }
+
+    const reader = response.body
+      .pipeThrough(new TextDecoderStream())
+      .getReader();
+    let buffer = "";
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) return;
+      buffer += value;
+
+      let end;
+      while ((end = buffer.indexOf("\n\n")) !== -1) {
+        const event = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+        const data = event
+          .split("\n")
+          .filter((line) => line.startsWith("data:"))
+          .map((line) => line.slice(5))
+          .join("\n");
+        if (data) onStatus(JSON.parse(data));
+      }
+    }
+  }
+
+  // Fresh presigned link to the PDF. Rejects (501) when storage can't presign.
+  async getDownloadUrl(
+    resumeId: string
+  ): Promise<{ url: string; expires_at: string }> {
+    const response = await axiosInstance.get(`/storage/${resumeId}/url`);
+    return response.data;
   }
 
   async downloadResume(resumeId: string): Promise<Blob> {
@@ -2,6 +2,7 @@ import { useState } from "react";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
 import { Resume } from "@/resumes/types";
+import { previewUrl } from "@/resumes/renditions";
 import {
   AnimatedButton,
   MessageSquareMoreIcon,
@@ -16,11 +17,7 @@ import { ResumeViewerModal } from "@/resumes/components/resume-viewer-modal";
 
 interface ResumeCardProps {
   resume: Resume;
-  onDelete: (
-    resumeId: string,
-    imageKeyPrefix: string,
-    pdfStorageKey: string
-  ) => Promise<void>;
+  onDelete: (resumeId: string) => Promise<void>;
   onDownload: (
     resumeId: string,
     resumeName: string,
@@ -48,8 +45,7 @@ export function ResumeCard({
   const [isViewerOpen, setIsViewerOpen] = useState(false);
   const [viewerData, setViewerData] = useState<{
     resumeName: string;
-    imageKeyPrefix: string;
-    cdnUrl: string;
+    imageUrl: string;
   } | null>(null);
 
   const handleViewResume = () => {
@@ -130,13 +126,7 @@ export function ResumeCard({
                 onView={handleViewResume}
                 onViewFeedback={() => onViewFeedback(resume.ID)}
                 onViewPerformance={() => onViewPerformance(resume.ID)}
-                onDelete={() =>
-                  onDelete(
-                    resume.ID,
-                    resume.ImageKeyPrefix,
-                    resume.PdfStorageKey
-                  )
-                }
+                onDelete={() => onDelete(resume.ID)}
                 onRename={(id, newName) => onRename(id, newName, resume.Name)}
                 onDownload={() =>
                   onDownload(resume.ID, resume.Name, resume.PdfStorageKey)
@@ -150,7 +140,7 @@ export function ResumeCard({
             <div className="aspect-[3/4] bg-muted rounded-lg flex items-center justify-center">
               <Lens zoomFactor={2.0} lensSize={300}>
                 <Image
-                  src={`${process.env.NEXT_PUBLIC_CDN_URL}${resume.ImageKeyPrefix}`}
+                  src={previewUrl(resume, 800)}
                   alt={resume.Name}
                   width={1000}
                   height={1000}
@@ -214,8 +204,7 @@ export function ResumeCard({
           isOpen={isViewerOpen}
           onClose={() => setIsViewerOpen(false)}
           resumeName={viewerData.resumeName}
-          imageKeyPrefix={viewerData.imageKeyPrefix}
-          cdnUrl={viewerData.cdnUrl}
+          imageUrl={viewerData.imageUrl}
         />
       )}
     </>
@@ -16,16 +16,14 @@ interface ResumeViewerModalProps {
   isOpen: boolean;
   onClose: () => void;
   resumeName: string;
-  imageKeyPrefix: string;
-  cdnUrl: string;
+  imageUrl: string;
 }
 
 export function ResumeViewerModal({
   isOpen,
   onClose,
   resumeName,
-  imageKeyPrefix,
-  cdnUrl,
+  imageUrl,
 }: ResumeViewerModalProps) {
   const [rotation, setRotation] = useState(0);
 
@@ -110,7 +108,7 @@ export function ResumeViewerModal({
                     }}
                   >
                     <Image
-                      src={`${cdnUrl}${imageKeyPrefix}`}
+                      src={imageUrl}
                       alt={resumeName}
                       width={1600}
                       height={2000}
@@ -0,0 +1,30 @@
+import { Rendition, Resume } from "./types";
+
+// First page's narrowest rendition at least minWidth wide (or the widest available).
+function pickRendition(resume: Resume, minWidth: number): Rendition | undefined {
+  const renditions =
+    resume.Pages?.[0]?.renditions ??
+    resume.ImageManifest?.pages?.[0]?.renditions ??
+    [];
+  if (renditions.length === 0) return undefined;
+
+  return (
+    renditions.find((r) => r.width >= minWidth) ??
+    renditions[renditions.length - 1]
+  );
+}
+
+// Key of the preview rendition. Resumes rendered before manifests existed store a
+// single key instead.
+export function previewKey(resume: Resume, minWidth: number): string {
+  return pickRendition(resume, minWidth)?.key ?? resume.ImageKeyPrefix;
+}
+
+// URL of the preview rendition, preferring the one the API built from its CDN host.
+export function previewUrl(resume: Resume, minWidth: number): string {
+  const rendition = pickRendition(resume, minWidth);
+  return (
+    rendition?.url ??
+    `${process.env.NEXT_PUBLIC_CDN_URL ?? ""}${previewKey(resume, minWidth)}`
+  );
+}
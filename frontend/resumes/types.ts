@@ -16,4 +16,58 @@ export interface Resume {
   PageCount: number;
   ImageReady: boolean;
   Slot: number;
+  HideCompanies: boolean;
+  ProcessingState: ProcessingState;
+  ProcessingError: string | null;
+  ImageManifest: ImageManifest | null;
+  CurrentVersion: number;
+  // Short-lived presigned link; absent when storage can't presign.
+  PdfURL?: string;
+  PdfURLExpiresAt?: string;
+  Pages: PageRenditions[];
+}
+
+export interface Rendition {
+  key: string;
+  width: number;
+  height: number;
+  // Public CDN address, filled in by the API.
+  url?: string;
+}
+
+export interface PageRenditions {
+  page: number;
+  renditions: Rendition[];
+}
+
+export interface ImageManifest {
+  version: number;
+  pages: PageRenditions[];
+}
+
+export type ProcessingState =
+  | "queued"
+  | "rendering"
+  | "redacting"
+  | "ready"
+  | "failed";
+
+export interface ProcessingStatus {
+  resume_id: string;
+  processing_state: ProcessingState;
+  processing_error?: string;
+  image_ready: boolean;
+  image_key_prefix: string;
+  pages: PageRenditions[];
+}
+
+export interface ResumeVersion {
+  version: number;
+  current: boolean;
+  page_count: number;
+  image_key_prefix: string;
+  pages: PageRenditions[];
+  final_elo?: number;
+  final_battles?: number;
+  created_at: string;
 }
@@ -2,9 +2,13 @@ import { useState, useEffect, useCallback } from "react";
 import { Resume } from "@/resumes/types";
 import { Activity } from "@/resumes/components/recent-activity";
 import { resumeApi } from "@/resumes/api";
+import { previewUrl } from "@/resumes/renditions";
 import { useAuth } from "@/components/auth-provider";
 import { useToast } from "@/components/ui/toast-context";
 
+// How long to wait before reopening a status stream that failed.
+const STATUS_RECONNECT_DELAY_MS = 5000;
+
 interface ResumeStats {
   totalResumes: number;
   bestElo: number;
@@ -99,14 +103,10 @@ export function useResumes() {
     }
   };
 
-  const deleteResume = async (
-    resumeId: string,
-    imageKeyPrefix: string,
-    pdfStorageKey: string
-  ) => {
+  const deleteResume = async (resumeId: string) => {
     try {
       setError(null);
-      await resumeApi.deleteResume(resumeId, imageKeyPrefix, pdfStorageKey);
+      await resumeApi.deleteResume(resumeId);
       await fetchResumes();
       showToast({
         type: "success",
@@ -159,15 +159,23 @@ export function useResumes() {
   const downloadResume = async (resumeId: string, resumeName: string) => {
     try {
       setError(null);
-      const blob = await resumeApi.downloadResume(resumeId);
-      const url = window.URL.createObjectURL(blob);
       const link = document.createElement("a");
-      link.href = url;
-      link.download = `${resumeName}.pdf`;
+      const direct = await resumeApi
+        .getDownloadUrl(resumeId)
+        .then((res) => res.url)
+        .catch(() => null);
+      if (direct) {
+        // The presigned link sets Content-Disposition, so the bucket names the file.
+        link.href = direct;
+      } else {
+        const blob = await resumeApi.downloadResume(resumeId);
+        link.href = window.URL.createObjectURL(blob);
+        link.download = `${resumeName}.pdf`;
+      }
       document.body.appendChild(link);
       link.click();
       document.body.removeChild(link);
-      window.URL.revokeObjectURL(url);
+      if (!direct) window.URL.revokeObjectURL(link.href);
       showToast({
         type: "success",
         title: "Download started",
@@ -211,8 +219,7 @@ export function useResumes() {
       // Return the resume data for the modal to use
       return {
         resumeName: resume.Name,
-        imageKeyPrefix: resume.ImageKeyPrefix,
-        cdnUrl: process.env.NEXT_PUBLIC_CDN_URL || "",
+        imageUrl: previewUrl(resume, 1600),
       };
     },
     [resumes, showToast]
@@ -244,6 +251,67 @@ export function useResumes() {
     fetchResumes();
   }, [user?.id]);
 
+  // Follow resumes still being processed so the page updates without polling.
+  const pendingIds = resumes
+    .filter(
+      (r) => r.ProcessingState !== "ready" && r.ProcessingState !== "failed"
+    )
+    .map((r) => r.ID)
+    .join(",");
+
+  useEffect(() => {
+    if (!pendingIds) return;
+
+    const controller = new AbortController();
+    const { signal } = controller;
+
+    // The server closes streams after a few minutes, and retries can sit in backoff
+    // for longer than that, so keep reconnecting until the resume is done.
+    const follow = async (id: string) => {
+      let done = false;
+      while (!done && !signal.aborted) {
+        try {
+          await resumeApi.watchStatus(
+            id,
+            (status) => {
+              done =
+                status.processing_state === "ready" ||
+                status.processing_state === "failed";
+              setResumes((prev) =>
+                prev.map((r) =>
+                  r.ID === status.resume_id
+                    ? {
+                        ...r,
+                        ProcessingState: status.processing_state,
+                        ProcessingError: status.processing_error ?? null,
+                        ImageReady: status.image_ready,
+                        ImageKeyPrefix: status.image_key_prefix,
+                        ImageManifest: { version: 1, pages: status.pages },
+                        // Pages is read before the manifest, so replace it too.
+                        Pages: status.pages ?? [],
+                      }
+                    : r
+                )
+              );
+            },
+            signal
+          );
+        } catch {
+          // Dropped or refused; wait a little before trying again.
+          if (signal.aborted) return;
+          await new Promise((resolve) =>
+            setTimeout(resolve, STATUS_RECONNECT_DELAY_MS)
+          );
+        }
+      }
+    };
+
+    for (const id of pendingIds.split(",")) {
+      void follow(id);
+    }
+    return () => controller.abort();
+  }, [pendingIds]);
+
   return {
     // State
     resumes,